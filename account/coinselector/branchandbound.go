@@ -0,0 +1,83 @@
+package coinselector
+
+import "sort"
+
+// defaultCostOfChange approximates the extra neu it costs to add a change
+// output and later spend it (an extra output plus an extra input down the
+// line), in the same neu units as UTXO amounts. It only needs to be in the
+// right ballpark: it is used purely to decide whether an exact match is
+// worth searching for.
+const defaultCostOfChange = 10000
+
+// defaultMaxTries bounds how many branches the depth-first search explores
+// before giving up on finding an exact (no-change) match.
+const defaultMaxTries = 100000
+
+// branchAndBound implements a simplified version of Bitcoin Core's
+// branch-and-bound coin selection: depth-first search over UTXOs sorted
+// descending, preferring combinations that exactly hit the target (within
+// costOfChange) so the transaction doesn't need a change output at all.
+type branchAndBound struct {
+	costOfChange uint64
+	maxTries     int
+	fallback     CoinSelector
+}
+
+func (s *branchAndBound) Select(candidates []*Candidate, targetAmount uint64) (*Result, error) {
+	sorted := make([]*Candidate, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Amount > sorted[j].Amount })
+
+	best, tries := s.search(sorted, targetAmount)
+	if best != nil {
+		recordChange(BranchAndBound, best.Change)
+		return best, nil
+	}
+
+	// No exact match within the try budget; fall back to a strategy that
+	// always succeeds given sufficient funds, accepting a change output.
+	_ = tries
+	return s.fallback.Select(candidates, targetAmount)
+}
+
+// search walks the selection tree depth-first. At each UTXO it tries both
+// including and excluding it, pruning as soon as the running sum would
+// exceed target + costOfChange (since sorted descending, any further
+// inclusion only grows the sum). It returns the first combination found
+// whose sum lands in [target, target+costOfChange], i.e. change so small it
+// isn't worth a dedicated output.
+func (s *branchAndBound) search(sorted []*Candidate, target uint64) (*Result, int) {
+	tries := 0
+	var selection []*Candidate
+
+	var walk func(i int, sum uint64) *Result
+	walk = func(i int, sum uint64) *Result {
+		tries++
+		if tries > s.maxTries {
+			return nil
+		}
+		if sum >= target {
+			if sum-target <= s.costOfChange {
+				out := make([]*Candidate, len(selection))
+				copy(out, selection)
+				return &Result{Selected: out, Change: sum - target}
+			}
+			return nil // overshot past what counts as "no change"; backtrack
+		}
+		if i >= len(sorted) {
+			return nil
+		}
+
+		// include sorted[i]
+		selection = append(selection, sorted[i])
+		if res := walk(i+1, sum+sorted[i].Amount); res != nil {
+			return res
+		}
+		selection = selection[:len(selection)-1]
+
+		// exclude sorted[i]
+		return walk(i+1, sum)
+	}
+
+	return walk(0, 0), tries
+}
@@ -0,0 +1,56 @@
+// Package coinselector implements pluggable strategies for picking which
+// unspent outputs a spend_account action reserves. account.AccountMgr used
+// to always coalesce every candidate UTXO via MergeSpendAction; a
+// CoinSelector instead picks a subset aimed at a specific target, so the
+// build-transaction caller can trade off change-output size against how
+// many UTXOs (and how much proof data) a transaction ends up spending.
+package coinselector
+
+import "github.com/bytom/protocol/bc"
+
+// Candidate is the minimal view of a spendable UTXO a CoinSelector needs.
+// The api package's selectSpendUTXOs maps account.UTXO into this before
+// calling Select.
+type Candidate struct {
+	OutputID bc.Hash
+	Amount   uint64
+}
+
+// Result is what a CoinSelector chose.
+type Result struct {
+	Selected []*Candidate
+	// Change is how much of the selected amount is left over after paying
+	// the target: the selected sum minus targetAmount. The cost of adding a
+	// change output is not subtracted from it.
+	Change uint64
+}
+
+// CoinSelector picks a subset of candidates whose amounts sum to at least
+// targetAmount.
+type CoinSelector interface {
+	Select(candidates []*Candidate, targetAmount uint64) (*Result, error)
+}
+
+// Strategy names accepted by BuildRequest.CoinSelection.
+const (
+	LargestFirst    = "largest-first"
+	BranchAndBound  = "branch-and-bound"
+	KnapsackRandom  = "knapsack-random"
+	defaultStrategy = LargestFirst
+)
+
+// New returns the CoinSelector registered under name, or the default
+// (largest-first, matching the repo's previous always-merge behavior
+// closest) if name is empty or unrecognized.
+func New(name string) CoinSelector {
+	switch name {
+	case BranchAndBound:
+		return &branchAndBound{costOfChange: defaultCostOfChange, maxTries: defaultMaxTries, fallback: &knapsackRandom{}}
+	case KnapsackRandom:
+		return &knapsackRandom{}
+	case LargestFirst, "":
+		return &largestFirst{}
+	default:
+		return &largestFirst{}
+	}
+}
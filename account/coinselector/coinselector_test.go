@@ -0,0 +1,114 @@
+package coinselector
+
+import "testing"
+
+func sumSelected(cands []*Candidate) uint64 {
+	var sum uint64
+	for _, c := range cands {
+		sum += c.Amount
+	}
+	return sum
+}
+
+func testCandidates(amounts ...uint64) []*Candidate {
+	out := make([]*Candidate, len(amounts))
+	for i, a := range amounts {
+		out[i] = &Candidate{Amount: a}
+	}
+	return out
+}
+
+func TestLargestFirstPrefersFewestInputs(t *testing.T) {
+	cands := testCandidates(10, 50, 100, 5)
+	res, err := (&largestFirst{}).Select(cands, 60)
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if len(res.Selected) != 1 {
+		t.Fatalf("expected a single 100-amount UTXO to cover the target, got %d inputs", len(res.Selected))
+	}
+	if res.Change != 40 {
+		t.Fatalf("expected change of 40, got %d", res.Change)
+	}
+}
+
+func TestLargestFirstInsufficientFunds(t *testing.T) {
+	cands := testCandidates(1, 2, 3)
+	if _, err := (&largestFirst{}).Select(cands, 100); err == nil {
+		t.Fatal("expected an error when candidates can't cover the target")
+	}
+}
+
+func TestBranchAndBoundFindsExactMatch(t *testing.T) {
+	cands := testCandidates(5, 15, 20, 30)
+	bb := &branchAndBound{costOfChange: 1, maxTries: defaultMaxTries, fallback: &largestFirst{}}
+
+	res, err := bb.Select(cands, 35)
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if sumSelected(res.Selected) != 35 {
+		t.Fatalf("expected an exact match summing to 35, got sum %d from %d inputs", sumSelected(res.Selected), len(res.Selected))
+	}
+	if res.Change != 0 {
+		t.Fatalf("expected zero change for an exact match, got %d", res.Change)
+	}
+}
+
+func TestBranchAndBoundFallsBackWhenNoExactMatch(t *testing.T) {
+	cands := testCandidates(7, 11, 13)
+	bb := &branchAndBound{costOfChange: 0, maxTries: defaultMaxTries, fallback: &largestFirst{}}
+
+	// No subset of 7, 11, 13 sums to exactly 21.
+	res, err := bb.Select(cands, 21)
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if sumSelected(res.Selected) < 21 {
+		t.Fatalf("fallback selection should still cover the target, got sum %d", sumSelected(res.Selected))
+	}
+}
+
+func TestKnapsackRandomCoversTarget(t *testing.T) {
+	cands := testCandidates(3, 4, 5, 6, 7)
+	res, err := (&knapsackRandom{}).Select(cands, 12)
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if sumSelected(res.Selected) < 12 {
+		t.Fatalf("expected selection sum >= target, got %d", sumSelected(res.Selected))
+	}
+}
+
+func TestNewReturnsExpectedStrategy(t *testing.T) {
+	if _, ok := New(LargestFirst).(*largestFirst); !ok {
+		t.Error("New(LargestFirst) did not return a largestFirst selector")
+	}
+	if _, ok := New(BranchAndBound).(*branchAndBound); !ok {
+		t.Error("New(BranchAndBound) did not return a branchAndBound selector")
+	}
+	if _, ok := New(KnapsackRandom).(*knapsackRandom); !ok {
+		t.Error("New(KnapsackRandom) did not return a knapsackRandom selector")
+	}
+	if _, ok := New("").(*largestFirst); !ok {
+		t.Error("New(\"\") should default to largestFirst")
+	}
+}
+
+func TestAverageChangeTracksSelections(t *testing.T) {
+	strategy := LargestFirst + "-metrics-test"
+	if avg, count := AverageChange(strategy); avg != 0 || count != 0 {
+		t.Fatalf("expected no data for an unused strategy name, got avg=%v count=%v", avg, count)
+	}
+
+	recordChange(strategy, 10)
+	recordChange(strategy, 20)
+
+	avg, count := AverageChange(strategy)
+	if count != 2 {
+		t.Fatalf("expected count 2, got %d", count)
+	}
+	if avg != 15 {
+		t.Fatalf("expected average of 15, got %v", avg)
+	}
+}
@@ -0,0 +1,37 @@
+package coinselector
+
+import (
+	"math/rand"
+
+	"github.com/bytom/errors"
+)
+
+// knapsackRandom shuffles the candidate set and greedily takes UTXOs off
+// the shuffled order until the target is met. It trades a larger, more
+// randomized input set (worse for fees, better for the wallet's future
+// privacy and UTXO diversity) for branch-and-bound's preference for exact
+// matches.
+type knapsackRandom struct{}
+
+func (s *knapsackRandom) Select(candidates []*Candidate, targetAmount uint64) (*Result, error) {
+	shuffled := make([]*Candidate, len(candidates))
+	copy(shuffled, candidates)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	var selected []*Candidate
+	var sum uint64
+	for _, c := range shuffled {
+		if sum >= targetAmount {
+			break
+		}
+		selected = append(selected, c)
+		sum += c.Amount
+	}
+
+	if sum < targetAmount {
+		return nil, errors.New("coinselector: insufficient funds to cover the target amount")
+	}
+
+	recordChange(KnapsackRandom, sum-targetAmount)
+	return &Result{Selected: selected, Change: sum - targetAmount}, nil
+}
@@ -0,0 +1,35 @@
+package coinselector
+
+import (
+	"sort"
+
+	"github.com/bytom/errors"
+)
+
+// largestFirst spends the biggest UTXOs first. It minimizes the number of
+// inputs (and therefore the signature data a transaction carries) at the
+// cost of usually leaving a larger change output than the other strategies.
+type largestFirst struct{}
+
+func (s *largestFirst) Select(candidates []*Candidate, targetAmount uint64) (*Result, error) {
+	sorted := make([]*Candidate, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Amount > sorted[j].Amount })
+
+	var selected []*Candidate
+	var sum uint64
+	for _, c := range sorted {
+		if sum >= targetAmount {
+			break
+		}
+		selected = append(selected, c)
+		sum += c.Amount
+	}
+
+	if sum < targetAmount {
+		return nil, errors.New("coinselector: insufficient funds to cover the target amount")
+	}
+
+	recordChange(LargestFirst, sum-targetAmount)
+	return &Result{Selected: selected, Change: sum - targetAmount}, nil
+}
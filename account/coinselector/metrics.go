@@ -0,0 +1,43 @@
+package coinselector
+
+import "sync"
+
+// strategyStats accumulates the running average change-output size a
+// strategy has produced, so operators can tell e.g. whether branch-and-bound
+// is actually finding exact matches in practice on their node.
+type strategyStats struct {
+	count  uint64
+	sumAvg float64
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[string]*strategyStats{}
+)
+
+func recordChange(strategy string, change uint64) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	s, ok := stats[strategy]
+	if !ok {
+		s = &strategyStats{}
+		stats[strategy] = s
+	}
+	s.count++
+	// incremental mean, avoids needing to keep every sample around
+	s.sumAvg += (float64(change) - s.sumAvg) / float64(s.count)
+}
+
+// AverageChange returns the running average change-output size produced by
+// strategy so far, and how many selections contributed to it.
+func AverageChange(strategy string) (avg float64, count uint64) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	s, ok := stats[strategy]
+	if !ok {
+		return 0, 0
+	}
+	return s.sumAvg, s.count
+}
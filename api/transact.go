@@ -3,21 +3,28 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/bytom/account"
+	"github.com/bytom/account/coinselector"
+	"github.com/bytom/blockchain/gasoracle"
 	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/blockchain/txbuilder/psbt"
 	"github.com/bytom/consensus"
 	"github.com/bytom/consensus/segwit"
 	"github.com/bytom/crypto/sha3pool"
 	"github.com/bytom/errors"
+	"github.com/bytom/mainchain"
 	"github.com/bytom/math/checked"
 	"github.com/bytom/net/http/reqid"
 	"github.com/bytom/protocol/bc"
@@ -59,6 +66,14 @@ func onlyHaveInputActions(req *BuildRequest) (bool, error) {
 }
 
 func (a *API) buildSingle(ctx context.Context, req *BuildRequest) (*txbuilder.Template, error) {
+	return a.buildSingleGuarded(ctx, req, nil, 0)
+}
+
+// buildSingleGuarded is buildSingle plus an optional utxoReservationGuard.
+// build-transactions passes a guard shared across the whole batch so two
+// requests in the same call cannot both reserve the same explicitly-named
+// unspent output; build passes a nil guard since there is only one request.
+func (a *API) buildSingleGuarded(ctx context.Context, req *BuildRequest, guard *utxoReservationGuard, reqIndex int) (*txbuilder.Template, error) {
 	if err := a.completeMissingIDs(ctx, req); err != nil {
 		return nil, err
 	}
@@ -69,6 +84,14 @@ func (a *API) buildSingle(ctx context.Context, req *BuildRequest) (*txbuilder.Te
 		return nil, errors.WithDetail(ErrBadActionConstruction, "transaction contains only input actions and no output actions")
 	}
 
+	// claimedOutputs tracks every output ID already spoken for by an earlier
+	// action within this single request, so a second spend_account action
+	// for the same account+asset doesn't have the coinselector hand back a
+	// UTXO an earlier action in this request already claimed. guard (when
+	// non-nil) only protects against collisions *across* requests in a
+	// batch; this map is what protects against collisions *within* one.
+	claimedOutputs := make(map[string]bool)
+
 	actions := make([]txbuilder.Action, 0, len(req.Actions))
 	for i, act := range req.Actions {
 		typ, ok := act["type"].(string)
@@ -80,6 +103,55 @@ func (a *API) buildSingle(ctx context.Context, req *BuildRequest) (*txbuilder.Te
 			return nil, errors.WithDetailf(ErrBadActionType, "unknown action type %q on action %d", typ, i)
 		}
 
+		if typ == "spend_account_unspent_output" {
+			if outputID, ok := act["output_id"].(string); ok && outputID != "" {
+				if guard != nil {
+					if err := guard.claim(outputID, reqIndex); err != nil {
+						return nil, err
+					}
+				}
+				claimedOutputs[outputID] = true
+			}
+		}
+
+		// spend_account picks its own UTXOs lazily by default; run the
+		// chosen coinselector.CoinSelector up front instead and rewrite the
+		// action into one spend_account_unspent_output action per UTXO it
+		// picked, so the strategy actually controls what the transaction
+		// spends rather than just riding along as an unused field.
+		if typ == "spend_account" {
+			strategy := req.CoinSelection
+			if cs, ok := act["coin_selection"].(string); ok && cs != "" {
+				strategy = cs
+			}
+
+			selected, err := a.selectSpendUTXOs(act, strategy, claimedOutputs)
+			if err != nil {
+				return nil, errors.WithDetailf(ErrBadAction, "%s on action %d", err.Error(), i)
+			}
+
+			for _, sa := range selected {
+				outputID := sa["output_id"].(string)
+				if guard != nil {
+					if err := guard.claim(outputID, reqIndex); err != nil {
+						return nil, err
+					}
+				}
+				claimedOutputs[outputID] = true
+
+				b, err := json.Marshal(sa)
+				if err != nil {
+					return nil, err
+				}
+				action, err := a.wallet.AccountMgr.DecodeSpendUTXOAction(b)
+				if err != nil {
+					return nil, errors.WithDetailf(ErrBadAction, "%s on action %d", err.Error(), i)
+				}
+				actions = append(actions, action)
+			}
+			continue
+		}
+
 		// Remarshal to JSON, the action may have been modified when we
 		// filtered aliases.
 		b, err := json.Marshal(act)
@@ -124,6 +196,52 @@ func (a *API) buildSingle(ctx context.Context, req *BuildRequest) (*txbuilder.Te
 	return tpl, nil
 }
 
+// selectSpendUTXOs resolves a spend_account action's account/asset/amount
+// into a concrete list of spend_account_unspent_output actions by running
+// strategy's coinselector.CoinSelector over that account's spendable UTXOs
+// for the asset. It is the one place a CoinSelector is actually invoked;
+// everything upstream of it only chooses which strategy to run.
+//
+// excluded lists output IDs already claimed by an earlier action in the
+// same request (explicitly, or by a previous call to selectSpendUTXOs) so
+// that two spend_account actions for the same account+asset don't have the
+// coinselector pick the same UTXO twice and produce a transaction that
+// spends one output twice over.
+func (a *API) selectSpendUTXOs(act map[string]interface{}, strategy string, excluded map[string]bool) ([]map[string]interface{}, error) {
+	accountID, _ := act["account_id"].(string)
+	assetID, _ := act["asset_id"].(string)
+	amount, _ := act["amount"].(float64)
+
+	utxos, err := a.wallet.AccountMgr.ListUnspentOutputs(accountID, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	byOutputID := make(map[bc.Hash]*account.UTXO, len(utxos))
+	candidates := make([]*coinselector.Candidate, 0, len(utxos))
+	for _, u := range utxos {
+		if excluded[u.OutputID.String()] {
+			continue
+		}
+		byOutputID[u.OutputID] = u
+		candidates = append(candidates, &coinselector.Candidate{OutputID: u.OutputID, Amount: u.Amount})
+	}
+
+	result, err := coinselector.New(strategy).Select(candidates, uint64(amount))
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]map[string]interface{}, 0, len(result.Selected))
+	for _, c := range result.Selected {
+		selected = append(selected, map[string]interface{}{
+			"type":      "spend_account_unspent_output",
+			"output_id": byOutputID[c.OutputID].OutputID.String(),
+		})
+	}
+	return selected, nil
+}
+
 // POST /build-transaction
 func (a *API) build(ctx context.Context, buildReqs *BuildRequest) Response {
 	subctx := reqid.NewSubContext(ctx, reqid.New())
@@ -136,6 +254,70 @@ func (a *API) build(ctx context.Context, buildReqs *BuildRequest) Response {
 	return NewSuccessResponse(tmpl)
 }
 
+// buildBatchWorkers bounds how many build-transactions requests are decoded
+// and merged concurrently in a single batch call.
+const buildBatchWorkers = 8
+
+// utxoReservationGuard prevents two requests within the same
+// build-transactions call from both reserving the same explicitly-named
+// unspent output. reqIndex is recorded rather than just a bool so a request
+// re-naming its own output (e.g. after completeMissingIDs) isn't mistaken
+// for a collision with another request.
+type utxoReservationGuard struct {
+	mu       sync.Mutex
+	reserved map[string]int
+}
+
+func newUTXOReservationGuard() *utxoReservationGuard {
+	return &utxoReservationGuard{reserved: make(map[string]int)}
+}
+
+func (g *utxoReservationGuard) claim(outputID string, reqIndex int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if owner, ok := g.reserved[outputID]; ok && owner != reqIndex {
+		return errors.WithDetailf(ErrBadActionConstruction, "output %s is reserved by another request in this batch", outputID)
+	}
+	g.reserved[outputID] = reqIndex
+	return nil
+}
+
+// buildBatch builds each of buildReqs on a bounded worker pool and returns
+// one Response per request, in order, so a single bad request doesn't abort
+// the rest of the batch.
+func (a *API) buildBatch(ctx context.Context, buildReqs []*BuildRequest) []Response {
+	responses := make([]Response, len(buildReqs))
+	guard := newUTXOReservationGuard()
+
+	sem := make(chan struct{}, buildBatchWorkers)
+	var wg sync.WaitGroup
+	for i, req := range buildReqs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, req *BuildRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			subctx := reqid.NewSubContext(ctx, reqid.New())
+			tmpl, err := a.buildSingleGuarded(subctx, req, guard, i)
+			if err != nil {
+				responses[i] = NewErrorResponse(err)
+				return
+			}
+			responses[i] = NewSuccessResponse(tmpl)
+		}(i, req)
+	}
+	wg.Wait()
+
+	return responses
+}
+
+// POST /build-transactions
+func (a *API) buildTransactions(ctx context.Context, buildReqs []*BuildRequest) Response {
+	return NewSuccessResponse(a.buildBatch(ctx, buildReqs))
+}
+
 type submitTxResp struct {
 	TxID *bc.Hash `json:"tx_id"`
 }
@@ -152,6 +334,158 @@ func (a *API) submit(ctx context.Context, ins struct {
 	return NewSuccessResponse(&submitTxResp{TxID: &ins.Tx.ID})
 }
 
+// submitMu serializes FinalizeTx across a submit-transactions batch.
+// txbuilder.FinalizeTx validates and inserts into the mempool in one call
+// with no exported way to split those two steps, so this lock covers all of
+// it, not just the insert - the batch endpoint does not parallelize
+// FinalizeTx itself. What running it through the worker pool in
+// buildBatchWorkers-sized batches still buys over N sequential calls to
+// /submit-transaction is everything around FinalizeTx: per-tx logging and
+// response assembly run concurrently, and one bad
+// tx's error doesn't block the rest of the batch from being tried.
+var submitMu sync.Mutex
+
+// POST /submit-transactions
+func (a *API) submitTransactions(ctx context.Context, ins struct {
+	Transactions []types.Tx `json:"raw_transactions"`
+}) Response {
+	responses := make([]Response, len(ins.Transactions))
+
+	sem := make(chan struct{}, buildBatchWorkers)
+	var wg sync.WaitGroup
+	for i := range ins.Transactions {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			tx := &ins.Transactions[i]
+
+			submitMu.Lock()
+			err := txbuilder.FinalizeTx(ctx, a.chain, tx)
+			submitMu.Unlock()
+			if err != nil {
+				responses[i] = NewErrorResponse(err)
+				return
+			}
+
+			log.WithField("tx_id", tx.ID.String()).Info("submit tx in batch")
+			responses[i] = NewSuccessResponse(&submitTxResp{TxID: &tx.ID})
+		}(i)
+	}
+	wg.Wait()
+
+	return NewSuccessResponse(responses)
+}
+
+// POST /sign-transactions
+func (a *API) signTransactions(ctx context.Context, ins struct {
+	Password  string                `json:"password"`
+	Templates []*txbuilder.Template `json:"transactions"`
+}) Response {
+	responses := make([]Response, len(ins.Templates))
+	for i, tpl := range ins.Templates {
+		if err := txbuilder.Sign(ctx, tpl, ins.Password, a.PseudohsmSignTemplate); err != nil {
+			log.WithField("build err", err).Error("fail on sign transaction in batch")
+			responses[i] = NewErrorResponse(err)
+			continue
+		}
+		responses[i] = NewSuccessResponse(tpl)
+	}
+
+	return NewSuccessResponse(responses)
+}
+
+// POST /encode-template
+//
+// encode-template converts a Bytom-specific Template into the portable
+// PSBT-style encoding, so it can be handed to a co-signer or an offline
+// signer that has never talked to this wallet.
+func (a *API) encodeTemplate(ctx context.Context, ins struct {
+	Template txbuilder.Template `json:"template"`
+}) Response {
+	raw, err := psbt.Encode(&ins.Template)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	return NewSuccessResponse(hex.EncodeToString(raw))
+}
+
+// POST /decode-template
+func (a *API) decodeTemplate(ctx context.Context, ins struct {
+	EncodedTemplate string `json:"encoded_template"`
+}) Response {
+	raw, err := hex.DecodeString(ins.EncodedTemplate)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
+	tpl, err := psbt.Decode(raw)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	return NewSuccessResponse(tpl)
+}
+
+// POST /combine-templates
+//
+// combine-templates merges the partial signatures N co-signers each added
+// to their own copy of the same unsigned template, deterministically, so
+// every caller combining the same set of signed templates gets byte-for-
+// byte the same result back.
+func (a *API) combineTemplates(ctx context.Context, ins struct {
+	EncodedTemplates []string `json:"encoded_templates"`
+}) Response {
+	tpls := make([]*txbuilder.Template, 0, len(ins.EncodedTemplates))
+	for _, enc := range ins.EncodedTemplates {
+		raw, err := hex.DecodeString(enc)
+		if err != nil {
+			return NewErrorResponse(err)
+		}
+		tpl, err := psbt.Decode(raw)
+		if err != nil {
+			return NewErrorResponse(err)
+		}
+		tpls = append(tpls, tpl)
+	}
+
+	merged, err := psbt.Combine(tpls)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
+	raw, err := psbt.Encode(merged)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	return NewSuccessResponse(hex.EncodeToString(raw))
+}
+
+// POST /finalize-template
+//
+// finalize-template turns a fully-signed template into a raw transaction
+// ready for POST /submit-transaction.
+func (a *API) finalizeTemplate(ctx context.Context, ins struct {
+	EncodedTemplate string `json:"encoded_template"`
+}) Response {
+	raw, err := hex.DecodeString(ins.EncodedTemplate)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
+	tpl, err := psbt.Decode(raw)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+
+	tx, err := psbt.Finalize(tpl)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	return NewSuccessResponse(tx)
+}
+
 // EstimateTxGasResp estimate transaction consumed gas
 type EstimateTxGasResp struct {
 	TotalNeu   int64 `json:"total_neu"`
@@ -159,8 +493,12 @@ type EstimateTxGasResp struct {
 	VMNeu      int64 `json:"vm_neu"`
 }
 
-// EstimateTxGas estimate consumed neu for transaction
-func EstimateTxGas(template txbuilder.Template) (*EstimateTxGasResp, error) {
+// EstimateTxGas estimate consumed neu for transaction. rate is typically one
+// of the tiers returned by GET /get-gas-rates: the storage and VM cost in neu
+// is rounded up to the next multiple of rate. The rate is not multiplied
+// into the gas, so a higher tier coarsens that rounding but does not scale
+// the estimate.
+func EstimateTxGas(template txbuilder.Template, rate float64) (*EstimateTxGasResp, error) {
 	// base tx size and not include sign
 	data, err := template.Transaction.TxData.MarshalText()
 	if err != nil {
@@ -204,12 +542,10 @@ func EstimateTxGas(template txbuilder.Template) (*EstimateTxGasResp, error) {
 	// total estimate gas
 	totalGas := totalTxSizeGas + totalP2WPKHGas + totalP2WSHGas
 
-	// rounding totalNeu with base rate 100000
-	totalNeu := float64(totalGas*consensus.VMGasRate) / defaultBaseRate
+	// rounding totalNeu to the chosen priority tier's rate
+	totalNeu := float64(totalGas*consensus.VMGasRate) / rate
 	roundingNeu := math.Ceil(totalNeu)
-	estimateNeu := int64(roundingNeu) * int64(defaultBaseRate)
-
-	// TODO add priority
+	estimateNeu := int64(roundingNeu) * int64(rate)
 
 	return &EstimateTxGasResp{
 		TotalNeu:   estimateNeu,
@@ -258,14 +594,69 @@ func estimateSignSize(signingInstructions []*txbuilder.SigningInstruction) int64
 // POST /estimate-transaction-gas
 func (a *API) estimateTxGas(ctx context.Context, in struct {
 	TxTemplate txbuilder.Template `json:"transaction_template"`
+	Priority   string             `json:"priority"`
 }) Response {
-	txGasResp, err := EstimateTxGas(in.TxTemplate)
+	rate, err := a.gasRateForPriority(in.Priority)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	txGasResp, err := EstimateTxGas(in.TxTemplate, rate)
 	if err != nil {
 		return NewErrorResponse(err)
 	}
 	return NewSuccessResponse(txGasResp)
 }
 
+// gasRateForPriority resolves a priority tier name, or an explicit
+// neu-per-gas rate given as a numeric string, to the rate to charge.
+// Falls back to the fixed defaultBaseRate if priority isn't an explicit
+// rate and the node has not wired up a gasoracle.Oracle (e.g. in tests).
+// An explicit rate that isn't strictly positive is rejected: EstimateTxGas
+// divides by this rate, so "0" would divide by zero and a negative rate
+// would flip the estimate's sign rather than produce a usable fee.
+func (a *API) gasRateForPriority(priority string) (float64, error) {
+	if rate, err := strconv.ParseFloat(priority, 64); err == nil {
+		if rate <= 0 {
+			return 0, errors.WithDetailf(errors.New("gas rate must be a positive number"), "rate %v is not positive", rate)
+		}
+		return rate, nil
+	}
+	if a.gasOracle == nil {
+		return defaultBaseRate, nil
+	}
+	return a.gasOracle.RateForTier(priority)
+}
+
+// GET /get-gas-rates
+func (a *API) getGasRates(ctx context.Context) Response {
+	if a.gasOracle == nil {
+		return NewSuccessResponse(gasoracle.Rates{Low: defaultBaseRate, Medium: defaultBaseRate, High: defaultBaseRate})
+	}
+	return NewSuccessResponse(a.gasOracle.Rates())
+}
+
+// parseMainchainTxID hashes the raw mainchain transaction bytes supplied with
+// a pegin claim so the SPV proof can be checked against that exact txid.
+// Mainchain txids are SHA256d (two rounds of plain SHA-256), not Bytom's own
+// SHA3 - using the wrong hash here would mean no genuine mainchain proof
+// could ever verify.
+func parseMainchainTxID(rawTxHex string) (bc.Hash, error) {
+	raw, err := hex.DecodeString(rawTxHex)
+	if err != nil {
+		return bc.Hash{}, errors.WithDetail(mainchain.ErrInvalidProof, "mainchain_transaction is not valid hex")
+	}
+
+	first := sha256.Sum256(raw)
+	second := sha256.Sum256(first[:])
+	// mainchain.ParseTxOutProof holds txids in display (byte-reversed) order,
+	// so the digest is reversed to match.
+	var txid [32]byte
+	for i := range second {
+		txid[i] = second[len(second)-1-i]
+	}
+	return bc.NewHash(txid), nil
+}
+
 func getPeginTxnOutputIndex(rawTx types.Tx, controlProg []byte) int {
 	for index, output := range rawTx.Outputs {
 		if bytes.Equal(output.ControlProgram, controlProg) {
@@ -280,10 +671,17 @@ func (a *API) claimPeginTx(ctx context.Context, ins struct {
 	RawTx       types.Tx `json:"raw_transaction"`
 	TxOutProof  string   `json:"tx_out_proof"`
 	ClaimScript string   `json:"claim_script"`
+	MainchainTx string   `json:"mainchain_transaction"`
 }) Response {
-	// raw transaction
-	// proof验证
-	// 增加spv验证以及连接主链api查询交易的确认数
+	// SPV验证: 校验tx_out_proof里的merkle分支能还原出主链区块头的merkle
+	// root，且该区块在主链头链上已经有足够的确认数，并拒绝重放的proof。
+	mainchainTxID, err := parseMainchainTxID(ins.MainchainTx)
+	if err != nil {
+		return NewErrorResponse(err)
+	}
+	if err := a.mainchainVerifier.VerifyClaim(ins.TxOutProof, mainchainTxID, ins.ClaimScript); err != nil {
+		return NewErrorResponse(err)
+	}
 
 	// 找出与claim script有关联的交易的输出
 	var address string
@@ -0,0 +1,153 @@
+// Package gasoracle tracks the gas prices actually paid by recent blocks so
+// the API can recommend a rate instead of using one fixed constant for
+// every transaction regardless of how busy the network currently is.
+package gasoracle
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/bytom/errors"
+)
+
+// Tier names accepted by the API's Priority field.
+const (
+	TierLow    = "low"
+	TierMedium = "medium"
+	TierHigh   = "high"
+)
+
+// Percentiles used for each tier. Low favors cheap-but-slower inclusion,
+// high favors next-block inclusion even during fee spikes.
+const (
+	lowPercentile    = 25
+	mediumPercentile = 50
+	highPercentile   = 90
+)
+
+// defaultHistory is how many of the most recent blocks are kept in the
+// ring buffer. 144 blocks is roughly a day at bytom's block time, enough to
+// smooth over short bursts without going stale.
+const defaultHistory = 144
+
+// Rates holds the three tier rates, each in neu-per-gas-unit.
+type Rates struct {
+	Low    float64 `json:"low"`
+	Medium float64 `json:"medium"`
+	High   float64 `json:"high"`
+}
+
+// Oracle keeps a rolling window of per-block gas-price histograms and
+// derives tiered fee-rate recommendations from them.
+type Oracle struct {
+	fallback float64
+
+	capacity int
+	blocks   [][]float64 // ring buffer of per-tx gas prices paid, one slice per block
+	next     int
+	filled   bool
+}
+
+// NewOracle returns an Oracle that retains the last `capacity` blocks of fee
+// data (defaultHistory if capacity <= 0) and falls back to fallbackRate
+// whenever no blocks have been observed yet.
+func NewOracle(capacity int, fallbackRate float64) *Oracle {
+	if capacity <= 0 {
+		capacity = defaultHistory
+	}
+	return &Oracle{
+		fallback: fallbackRate,
+		capacity: capacity,
+		blocks:   make([][]float64, capacity),
+	}
+}
+
+// OnBlockConnected records the gas price paid by every transaction in a
+// newly-connected block. It is meant to be called from the chain's
+// block-connected event handler. gasPrices may be empty for an empty block.
+func (o *Oracle) OnBlockConnected(gasPrices []float64) {
+	cp := make([]float64, len(gasPrices))
+	copy(cp, gasPrices)
+
+	o.blocks[o.next] = cp
+	o.next = (o.next + 1) % o.capacity
+	if o.next == 0 {
+		o.filled = true
+	}
+}
+
+// Rates returns the current low/medium/high tier rates computed from every
+// gas price paid across the tracked window. If no data has been observed
+// yet, every tier returns the fallback rate the Oracle was created with.
+func (o *Oracle) Rates() Rates {
+	samples := o.samples()
+	if len(samples) == 0 {
+		return Rates{Low: o.fallback, Medium: o.fallback, High: o.fallback}
+	}
+
+	sort.Float64s(samples)
+	return Rates{
+		Low:    percentile(samples, lowPercentile),
+		Medium: percentile(samples, mediumPercentile),
+		High:   percentile(samples, highPercentile),
+	}
+}
+
+// ErrInvalidRate is returned when an explicit numeric rate is not a usable
+// neu-per-gas price (e.g. zero or negative).
+var ErrInvalidRate = errors.New("gas rate must be a positive number")
+
+// RateForTier resolves a tier name (or an explicit numeric rate string) to a
+// neu-per-gas rate. A tier string that parses as a number is used directly
+// as the rate, so a caller who already knows the rate they want to pay
+// isn't silently charged the medium-tier rate instead; any other
+// unrecognized tier name falls back to the medium tier. An explicit rate
+// that isn't strictly positive is rejected: callers divide by this rate to
+// estimate fees, so a zero or negative rate would corrupt that math rather
+// than merely underpay it.
+func (o *Oracle) RateForTier(tier string) (float64, error) {
+	if rate, err := strconv.ParseFloat(tier, 64); err == nil {
+		if rate <= 0 {
+			return 0, errors.WithDetailf(ErrInvalidRate, "rate %v is not positive", rate)
+		}
+		return rate, nil
+	}
+
+	rates := o.Rates()
+	switch tier {
+	case TierLow:
+		return rates.Low, nil
+	case TierHigh:
+		return rates.High, nil
+	default:
+		return rates.Medium, nil
+	}
+}
+
+func (o *Oracle) samples() []float64 {
+	n := o.next
+	if o.filled {
+		n = o.capacity
+	}
+
+	var out []float64
+	for i := 0; i < n; i++ {
+		out = append(out, o.blocks[i]...)
+	}
+	return out
+}
+
+// percentile returns the p-th percentile (0-100) of a pre-sorted slice
+// using the nearest-rank method: the smallest sample with at least p% of
+// the samples at or below it. The result is always one of the samples.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
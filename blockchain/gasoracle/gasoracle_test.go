@@ -0,0 +1,82 @@
+package gasoracle
+
+import "testing"
+
+func TestRatesWithoutData(t *testing.T) {
+	o := NewOracle(10, 100000)
+	rates := o.Rates()
+	if rates.Low != 100000 || rates.Medium != 100000 || rates.High != 100000 {
+		t.Fatalf("expected fallback rate for every tier, got %+v", rates)
+	}
+}
+
+func TestRatesReplaySyntheticBlocks(t *testing.T) {
+	o := NewOracle(10, 100000)
+
+	// Replay three blocks with increasingly expensive fee markets.
+	o.OnBlockConnected([]float64{10, 20, 30, 40})
+	o.OnBlockConnected([]float64{50, 60, 70, 80})
+	o.OnBlockConnected([]float64{90, 100, 110, 120})
+
+	rates := o.Rates()
+	if rates.Low >= rates.Medium || rates.Medium >= rates.High {
+		t.Fatalf("expected low < medium < high, got %+v", rates)
+	}
+	if rates.High != 120 && rates.High < 110 {
+		t.Fatalf("expected high tier near the top of the distribution, got %v", rates.High)
+	}
+}
+
+func TestRatesRingBufferEviction(t *testing.T) {
+	o := NewOracle(2, 100000)
+
+	o.OnBlockConnected([]float64{1000, 1000})
+	o.OnBlockConnected([]float64{10, 10})
+	o.OnBlockConnected([]float64{20, 20}) // should evict the first, priciest block
+
+	rates := o.Rates()
+	if rates.High >= 1000 {
+		t.Fatalf("expected the evicted high-fee block to no longer affect rates, got %+v", rates)
+	}
+}
+
+func TestRateForTierUnknownFallsBackToMedium(t *testing.T) {
+	o := NewOracle(10, 100000)
+	o.OnBlockConnected([]float64{10, 20, 30})
+
+	got, err := o.RateForTier("bogus")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := o.RateForTier(TierMedium)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Fatalf("expected unknown tier to fall back to medium, got %v want %v", got, want)
+	}
+}
+
+func TestRateForTierAcceptsExplicitRate(t *testing.T) {
+	o := NewOracle(10, 100000)
+	o.OnBlockConnected([]float64{10, 20, 30})
+
+	got, err := o.RateForTier("12345.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := 12345.5; got != want {
+		t.Fatalf("expected an explicit numeric rate to be used as-is, got %v want %v", got, want)
+	}
+}
+
+func TestRateForTierRejectsNonPositiveExplicitRate(t *testing.T) {
+	o := NewOracle(10, 100000)
+	o.OnBlockConnected([]float64{10, 20, 30})
+
+	for _, rate := range []string{"0", "-1", "-12345.5"} {
+		if _, err := o.RateForTier(rate); err == nil {
+			t.Errorf("RateForTier(%q): expected an error, got nil", rate)
+		}
+	}
+}
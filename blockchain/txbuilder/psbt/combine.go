@@ -0,0 +1,119 @@
+package psbt
+
+import (
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/errors"
+)
+
+// ErrVersionMismatch is returned by Combine when the templates being merged
+// were not all built from the same unsigned transaction.
+var ErrVersionMismatch = errors.New("psbt: templates do not share the same unsigned transaction")
+
+// Combine merges the per-input signing state of N co-signers' templates,
+// all of which must have started from the same unsigned transaction, into
+// one template carrying every signature any of them contributed.
+//
+// Per input, each co-signer's WitnessComponents are merged position by
+// position: a txbuilder.SignatureWitness at a given slot is combined with
+// the same slot's SignatureWitness from every other co-signer by filling in
+// each Sigs index from whichever co-signer supplied it (first non-nil
+// wins), so two co-signers who each contributed disjoint signatures end up
+// as one witness with every slot filled, not two still-incomplete
+// witnesses sitting side by side. Any other Witness implementation at a
+// slot (e.g. a witness script) is identical across co-signers by
+// construction, so the first one seen is kept as-is.
+func Combine(templates []*txbuilder.Template) (*txbuilder.Template, error) {
+	if len(templates) == 0 {
+		return nil, errors.New("psbt: combine requires at least one template")
+	}
+
+	base := templates[0]
+	numInputs := len(base.Transaction.Inputs)
+	for _, tpl := range templates[1:] {
+		if len(tpl.Transaction.Inputs) != numInputs || tpl.Transaction.ID != base.Transaction.ID {
+			return nil, ErrVersionMismatch
+		}
+	}
+
+	merged := &txbuilder.Template{Transaction: base.Transaction}
+	for i := 0; i < numInputs; i++ {
+		var perSigner [][]txbuilder.Witness
+		for _, tpl := range templates {
+			if i >= len(tpl.SigningInstructions) || tpl.SigningInstructions[i] == nil {
+				continue
+			}
+			perSigner = append(perSigner, tpl.SigningInstructions[i].WitnessComponents)
+		}
+
+		components, err := mergeWitnessComponents(perSigner)
+		if err != nil {
+			return nil, err
+		}
+		merged.SigningInstructions = append(merged.SigningInstructions, &txbuilder.SigningInstruction{
+			WitnessComponents: components,
+		})
+	}
+
+	return merged, nil
+}
+
+// mergeWitnessComponents merges one input's WitnessComponents lists, one
+// per co-signer who had anything to say about that input, slot by slot.
+func mergeWitnessComponents(perSigner [][]txbuilder.Witness) ([]txbuilder.Witness, error) {
+	width := 0
+	for _, ws := range perSigner {
+		if len(ws) > width {
+			width = len(ws)
+		}
+	}
+	if width == 0 {
+		return nil, nil
+	}
+
+	merged := make([]txbuilder.Witness, width)
+	for pos := 0; pos < width; pos++ {
+		var atSlot []txbuilder.Witness
+		for _, ws := range perSigner {
+			if pos < len(ws) && ws[pos] != nil {
+				atSlot = append(atSlot, ws[pos])
+			}
+		}
+
+		w, err := mergeWitnessSlot(atSlot)
+		if err != nil {
+			return nil, err
+		}
+		merged[pos] = w
+	}
+	return merged, nil
+}
+
+// mergeWitnessSlot combines every co-signer's contribution to a single
+// WitnessComponents slot into one Witness.
+func mergeWitnessSlot(atSlot []txbuilder.Witness) (txbuilder.Witness, error) {
+	if len(atSlot) == 0 {
+		return nil, nil
+	}
+
+	first, ok := atSlot[0].(*txbuilder.SignatureWitness)
+	if !ok {
+		return atSlot[0], nil
+	}
+
+	merged := &txbuilder.SignatureWitness{Quorum: first.Quorum, Sigs: append([][]byte{}, first.Sigs...)}
+	for _, w := range atSlot[1:] {
+		sw, ok := w.(*txbuilder.SignatureWitness)
+		if !ok {
+			return nil, errors.New("psbt: mismatched witness types at the same signing slot")
+		}
+		if len(sw.Sigs) != len(merged.Sigs) {
+			return nil, errors.New("psbt: signature witnesses at the same slot disagree on signer count")
+		}
+		for i, sig := range sw.Sigs {
+			if merged.Sigs[i] == nil && sig != nil {
+				merged.Sigs[i] = sig
+			}
+		}
+	}
+	return merged, nil
+}
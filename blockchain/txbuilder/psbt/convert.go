@@ -0,0 +1,216 @@
+package psbt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc/types"
+)
+
+// Encode serializes tpl into the canonical PSBT-style binary format.
+func Encode(tpl *txbuilder.Template) ([]byte, error) {
+	unsignedTx, err := tpl.Transaction.TxData.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &container{
+		global: kvMap{{typ: keyGlobalUnsignedTx, value: unsignedTx}},
+	}
+
+	for i, inp := range tpl.Transaction.Inputs {
+		m := kvMap{}
+		if inp.IsPegin {
+			m = append(m, kv{typ: keyInPeginWitnessFlag, value: []byte{1}})
+			for _, stackItem := range inp.Peginwitness {
+				m = append(m, kv{typ: keyInPeginProof, value: stackItem})
+			}
+		}
+
+		if i < len(tpl.SigningInstructions) && tpl.SigningInstructions[i] != nil {
+			for pos, w := range tpl.SigningInstructions[i].WitnessComponents {
+				entries, err := encodeWitnessComponent(pos, w)
+				if err != nil {
+					return nil, err
+				}
+				m = append(m, entries...)
+			}
+		}
+		c.inputs = append(c.inputs, m)
+	}
+
+	for range tpl.Transaction.Outputs {
+		// No per-output metadata is produced by this wallet today, but the
+		// map is still emitted so the format has somewhere to grow into
+		// (e.g. a future bip32-style derivation hint for a change output).
+		c.outputs = append(c.outputs, kvMap{})
+	}
+
+	return encodeContainer(c), nil
+}
+
+// encodeWitnessComponent turns one WitnessComponents[pos] entry into its
+// field-level kv entries: a kind byte, the quorum, the JSON-encoded Keys
+// (txbuilder.Witness implementations don't expose a narrower typed encoding
+// for Keys), and - for a *SignatureWitness - each individual signature
+// slot, so a foreign signer only needs to understand one signature at a
+// time rather than the whole opaque struct.
+func encodeWitnessComponent(pos int, w txbuilder.Witness) (kvMap, error) {
+	idxKey := componentIndexKey(pos)
+
+	if w == nil {
+		return kvMap{{typ: keyInComponentKind, key: idxKey, value: []byte{witnessKindEmpty}}}, nil
+	}
+
+	var kind byte
+	var quorum int
+	var keysVal interface{}
+	var sigs [][]byte
+
+	switch t := w.(type) {
+	case *txbuilder.SignatureWitness:
+		kind = witnessKindSignature
+		quorum = t.Quorum
+		keysVal = t.Keys
+		sigs = t.Sigs
+	case *txbuilder.RawTxSigWitness:
+		kind = witnessKindRawTxSig
+		quorum = t.Quorum
+		keysVal = t.Keys
+	default:
+		return nil, errors.WithDetailf(ErrMalformed, "witness component %d has unsupported type %T", pos, w)
+	}
+
+	keysJSON, err := json.Marshal(keysVal)
+	if err != nil {
+		return nil, err
+	}
+
+	var quorumBuf [4]byte
+	binary.BigEndian.PutUint32(quorumBuf[:], uint32(quorum))
+
+	m := kvMap{
+		{typ: keyInComponentKind, key: idxKey, value: []byte{kind}},
+		{typ: keyInComponentQuorum, key: idxKey, value: quorumBuf[:]},
+		{typ: keyInComponentKeys, key: idxKey, value: keysJSON},
+	}
+
+	if sigs != nil {
+		var countBuf [4]byte
+		binary.BigEndian.PutUint32(countBuf[:], uint32(len(sigs)))
+		m = append(m, kv{typ: keyInComponentSigCount, key: idxKey, value: countBuf[:]})
+
+		for sigIdx, sig := range sigs {
+			if sig == nil {
+				continue
+			}
+			m = append(m, kv{typ: keyInComponentSig, key: sigSlotKey(pos, sigIdx), value: sig})
+		}
+	}
+
+	return m, nil
+}
+
+// Decode parses raw as a PSBT-style byte stream and reconstructs a
+// txbuilder.Template from it.
+func Decode(raw []byte) (*txbuilder.Template, error) {
+	c, err := decodeContainer(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	unsignedTx, ok := c.global.get(keyGlobalUnsignedTx, nil)
+	if !ok {
+		return nil, errors.WithDetail(ErrMalformed, "missing unsigned transaction in global map")
+	}
+
+	txData := &types.TxData{}
+	if err := txData.UnmarshalText(unsignedTx); err != nil {
+		return nil, errors.Wrap(ErrMalformed, err.Error())
+	}
+	tx := types.NewTx(*txData)
+
+	tpl := &txbuilder.Template{Transaction: &tx}
+	for i, m := range c.inputs {
+		if _, isPegin := m.get(keyInPeginWitnessFlag, nil); isPegin {
+			tx.Inputs[i].IsPegin = true
+			for _, e := range m.getAll(keyInPeginProof) {
+				tx.Inputs[i].Peginwitness = append(tx.Inputs[i].Peginwitness, e.value)
+			}
+		}
+
+		sigInst, err := decodeSigningInstruction(m)
+		if err != nil {
+			return nil, err
+		}
+		tpl.SigningInstructions = append(tpl.SigningInstructions, sigInst)
+	}
+
+	return tpl, nil
+}
+
+// decodeSigningInstruction reconstructs one input's SigningInstruction from
+// its field-level kv entries, in the same component order Encode wrote them
+// in (kvMap.getAll preserves insertion order, and Encode always walks
+// WitnessComponents in ascending position order).
+func decodeSigningInstruction(m kvMap) (*txbuilder.SigningInstruction, error) {
+	sigInst := &txbuilder.SigningInstruction{}
+
+	for _, kindEntry := range m.getAll(keyInComponentKind) {
+		if len(kindEntry.key) != 4 || len(kindEntry.value) != 1 {
+			return nil, errors.WithDetail(ErrMalformed, "malformed witness component kind entry")
+		}
+		pos := int(binary.BigEndian.Uint32(kindEntry.key))
+
+		if kindEntry.value[0] == witnessKindEmpty {
+			sigInst.WitnessComponents = append(sigInst.WitnessComponents, nil)
+			continue
+		}
+
+		quorumVal, ok := m.get(keyInComponentQuorum, kindEntry.key)
+		if !ok || len(quorumVal) != 4 {
+			return nil, errors.WithDetailf(ErrMalformed, "witness component %d missing quorum", pos)
+		}
+		quorum := int(binary.BigEndian.Uint32(quorumVal))
+
+		keysJSON, ok := m.get(keyInComponentKeys, kindEntry.key)
+		if !ok {
+			return nil, errors.WithDetailf(ErrMalformed, "witness component %d missing keys", pos)
+		}
+
+		var w txbuilder.Witness
+		switch kindEntry.value[0] {
+		case witnessKindSignature:
+			sw := &txbuilder.SignatureWitness{Quorum: quorum}
+			if err := json.Unmarshal(keysJSON, &sw.Keys); err != nil {
+				return nil, errors.Wrap(ErrMalformed, err.Error())
+			}
+			if countVal, ok := m.get(keyInComponentSigCount, kindEntry.key); ok {
+				if len(countVal) != 4 {
+					return nil, errors.WithDetailf(ErrMalformed, "witness component %d has malformed sig count", pos)
+				}
+				sw.Sigs = make([][]byte, binary.BigEndian.Uint32(countVal))
+				for sigIdx := range sw.Sigs {
+					if sigVal, ok := m.get(keyInComponentSig, sigSlotKey(pos, sigIdx)); ok {
+						sw.Sigs[sigIdx] = sigVal
+					}
+				}
+			}
+			w = sw
+		case witnessKindRawTxSig:
+			rw := &txbuilder.RawTxSigWitness{Quorum: quorum}
+			if err := json.Unmarshal(keysJSON, &rw.Keys); err != nil {
+				return nil, errors.Wrap(ErrMalformed, err.Error())
+			}
+			w = rw
+		default:
+			return nil, errors.WithDetailf(ErrMalformed, "witness component %d has unknown kind %d", pos, kindEntry.value[0])
+		}
+
+		sigInst.WitnessComponents = append(sigInst.WitnessComponents, w)
+	}
+
+	return sigInst, nil
+}
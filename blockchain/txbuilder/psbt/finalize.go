@@ -0,0 +1,49 @@
+package psbt
+
+import (
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/errors"
+	"github.com/bytom/protocol/bc/types"
+)
+
+// ErrIncomplete is returned by Finalize when a template still has an input
+// missing its signing instruction, or carrying a SignatureWitness whose
+// quorum is not yet satisfied, i.e. it was never signed by enough parties.
+var ErrIncomplete = errors.New("psbt: template has an input that is not fully signed")
+
+// Finalize converts a fully-signed template into a raw transaction ready
+// for POST /submit-transaction. Combine can produce a SignatureWitness that
+// still has unfilled Sigs slots when not enough co-signers have contributed
+// yet, so Finalize checks every SignatureWitness's quorum explicitly
+// instead of assuming a non-empty WitnessComponents means complete.
+func Finalize(tpl *txbuilder.Template) (*types.Tx, error) {
+	if len(tpl.SigningInstructions) != len(tpl.Transaction.Inputs) {
+		return nil, ErrIncomplete
+	}
+	for _, sigInst := range tpl.SigningInstructions {
+		if sigInst == nil || len(sigInst.WitnessComponents) == 0 {
+			return nil, ErrIncomplete
+		}
+		for _, w := range sigInst.WitnessComponents {
+			sw, ok := w.(*txbuilder.SignatureWitness)
+			if !ok {
+				continue
+			}
+			if countSigs(sw.Sigs) < sw.Quorum {
+				return nil, ErrIncomplete
+			}
+		}
+	}
+
+	return tpl.Transaction, nil
+}
+
+func countSigs(sigs [][]byte) int {
+	n := 0
+	for _, s := range sigs {
+		if s != nil {
+			n++
+		}
+	}
+	return n
+}
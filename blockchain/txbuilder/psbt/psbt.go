@@ -0,0 +1,255 @@
+// Package psbt implements a portable, PSBT (BIP174)-style binary encoding
+// for txbuilder.Template. Unlike the Bytom-specific JSON a Template is
+// normally shipped as, this encoding carries no assumption that the
+// decoding side is the same wallet process that built the template: it is
+// meant to travel to an offline signer and back.
+//
+// The wire format is a sequence of key-value maps: one global map, one map
+// per transaction input, and one map per transaction output, each
+// terminated by a zero-length key (the BIP174 "separator"). Every key is
+// (type byte, key bytes); every value is length-prefixed bytes.
+//
+// Bytom's account programs have no equivalent of Bitcoin's witness scripts
+// or BIP32 derivation paths, so this format does not carry BIP174 key types
+// for them. What it does carry, field by field rather than as one opaque
+// blob, is each input's witness components: per component, a type code
+// (SignatureWitness or RawTxSigWitness), its quorum, its Keys (still JSON,
+// since txbuilder.Witness implementations don't expose a narrower typed
+// encoding of Keys), and - for SignatureWitness - each signature slot
+// individually, so a co-signer only has to understand "here is signature
+// slot 2 of 3 for component 0" rather than the whole SigningInstruction
+// struct. See convert.go.
+package psbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/bytom/errors"
+)
+
+// Global key types.
+const (
+	keyGlobalUnsignedTx byte = 0x00
+)
+
+// Per-input key types.
+const (
+	keyInPeginWitnessFlag byte = 0x01
+	keyInPeginProof       byte = 0x02
+
+	// keyInComponentKind, keyInComponentQuorum and keyInComponentKeys each
+	// use a 4-byte big-endian witness-component index as their key, so an
+	// input with N witness components has up to N entries of each.
+	keyInComponentKind   byte = 0x03 // value: 1 witnessKind byte
+	keyInComponentQuorum byte = 0x04 // value: 4-byte big-endian quorum
+	keyInComponentKeys   byte = 0x05 // value: JSON-encoded Keys
+
+	// keyInComponentSigCount uses the same 4-byte component-index key as
+	// above; keyInComponentSig uses an 8-byte key (component index, then
+	// signature-slot index, both big-endian) and is only present for slots
+	// that have actually been signed, so unsigned slots round-trip as nil
+	// instead of needing a placeholder value.
+	keyInComponentSigCount byte = 0x06
+	keyInComponentSig      byte = 0x07
+)
+
+// witnessKind identifies which concrete txbuilder.Witness implementation a
+// witness component's field-level entries (see above) decode back into.
+const (
+	witnessKindEmpty     byte = 0x00 // component slot present but nil
+	witnessKindSignature byte = 0x01 // *txbuilder.SignatureWitness
+	witnessKindRawTxSig  byte = 0x02 // *txbuilder.RawTxSigWitness
+)
+
+// magic identifies the byte stream as a Bytom PSBT-style template, the way
+// BIP174 uses the 4-byte magic 0x70736274.
+var magic = []byte{'b', 'p', 's', 't'}
+
+// componentIndexKey is the per-input map key shared by keyInComponentKind,
+// keyInComponentQuorum, keyInComponentKeys and keyInComponentSigCount for
+// the witness component at pos.
+func componentIndexKey(pos int) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(pos))
+	return b[:]
+}
+
+// sigSlotKey is the keyInComponentSig map key for signature slot sigIdx of
+// the witness component at pos.
+func sigSlotKey(pos, sigIdx int) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint32(b[0:4], uint32(pos))
+	binary.BigEndian.PutUint32(b[4:8], uint32(sigIdx))
+	return b[:]
+}
+
+// kv is one (key-type, key, value) entry within a map.
+type kv struct {
+	typ   byte
+	key   []byte
+	value []byte
+}
+
+// kvMap is an ordered set of kv entries; order is preserved so encoding is
+// deterministic given the same logical content.
+type kvMap []kv
+
+func (m kvMap) get(typ byte, key []byte) ([]byte, bool) {
+	for _, e := range m {
+		if e.typ == typ && bytes.Equal(e.key, key) {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+func (m kvMap) getAll(typ byte) kvMap {
+	var out kvMap
+	for _, e := range m {
+		if e.typ == typ {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func writeVarBytes(w io.Writer, b []byte) error {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+	if _, err := w.Write(lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func readVarBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	// n comes straight off the wire, so without this check a few bytes of
+	// attacker-supplied hex claiming a huge length would drive make([]byte,
+	// n) to attempt a multi-GB allocation before io.ReadFull ever got the
+	// chance to fail with a normal, recoverable "short read" error.
+	if n > uint64(r.Len()) {
+		return nil, errors.New("psbt: length prefix exceeds remaining data")
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func writeMap(w io.Writer, m kvMap) error {
+	for _, e := range m {
+		if err := writeVarBytes(w, []byte{e.typ}); err != nil {
+			return err
+		}
+		if err := writeVarBytes(w, e.key); err != nil {
+			return err
+		}
+		if err := writeVarBytes(w, e.value); err != nil {
+			return err
+		}
+	}
+	// zero-length type byte marks the end of this map, mirroring BIP174's
+	// separator byte.
+	return writeVarBytes(w, nil)
+}
+
+func readMap(r *bytes.Reader) (kvMap, error) {
+	var m kvMap
+	for {
+		typb, err := readVarBytes(r)
+		if err != nil {
+			return nil, errors.Wrap(ErrMalformed, err.Error())
+		}
+		if len(typb) == 0 {
+			return m, nil
+		}
+
+		key, err := readVarBytes(r)
+		if err != nil {
+			return nil, errors.Wrap(ErrMalformed, err.Error())
+		}
+		val, err := readVarBytes(r)
+		if err != nil {
+			return nil, errors.Wrap(ErrMalformed, err.Error())
+		}
+		m = append(m, kv{typ: typb[0], key: key, value: val})
+	}
+}
+
+// ErrMalformed is returned for any structurally invalid PSBT byte stream.
+var ErrMalformed = errors.New("psbt: malformed byte stream")
+
+// container is the fully-parsed form of a PSBT byte stream, before it is
+// interpreted as a txbuilder.Template.
+type container struct {
+	global  kvMap
+	inputs  []kvMap
+	outputs []kvMap
+}
+
+func encodeContainer(c *container) []byte {
+	buf := &bytes.Buffer{}
+	buf.Write(magic)
+
+	var numIn, numOut [4]byte
+	binary.LittleEndian.PutUint32(numIn[:], uint32(len(c.inputs)))
+	binary.LittleEndian.PutUint32(numOut[:], uint32(len(c.outputs)))
+	buf.Write(numIn[:])
+	buf.Write(numOut[:])
+
+	writeMap(buf, c.global)
+	for _, m := range c.inputs {
+		writeMap(buf, m)
+	}
+	for _, m := range c.outputs {
+		writeMap(buf, m)
+	}
+	return buf.Bytes()
+}
+
+func decodeContainer(raw []byte) (*container, error) {
+	if len(raw) < len(magic)+8 || !bytes.Equal(raw[:len(magic)], magic) {
+		return nil, errors.WithDetail(ErrMalformed, "missing psbt magic")
+	}
+	r := bytes.NewReader(raw[len(magic):])
+
+	var numInBuf, numOutBuf [4]byte
+	if _, err := io.ReadFull(r, numInBuf[:]); err != nil {
+		return nil, errors.Wrap(ErrMalformed, err.Error())
+	}
+	if _, err := io.ReadFull(r, numOutBuf[:]); err != nil {
+		return nil, errors.Wrap(ErrMalformed, err.Error())
+	}
+	numIn := binary.LittleEndian.Uint32(numInBuf[:])
+	numOut := binary.LittleEndian.Uint32(numOutBuf[:])
+
+	global, err := readMap(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &container{global: global}
+	for i := uint32(0); i < numIn; i++ {
+		m, err := readMap(r)
+		if err != nil {
+			return nil, err
+		}
+		c.inputs = append(c.inputs, m)
+	}
+	for i := uint32(0); i < numOut; i++ {
+		m, err := readMap(r)
+		if err != nil {
+			return nil, err
+		}
+		c.outputs = append(c.outputs, m)
+	}
+	return c, nil
+}
@@ -0,0 +1,275 @@
+package psbt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/protocol/bc/types"
+)
+
+// reencodeJSON round-trips v (a SignatureWitness.Keys value, whose concrete
+// type this package never assumes) through JSON into out, mirroring how
+// Decode itself recovers Keys.
+func reencodeJSON(v, out interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func newUnsignedTemplate(numInputs int) *txbuilder.Template {
+	txData := types.TxData{Version: 1}
+	for i := 0; i < numInputs; i++ {
+		txData.Inputs = append(txData.Inputs, &types.TxInput{})
+		txData.Outputs = append(txData.Outputs, &types.TxOutput{})
+	}
+	tx := types.NewTx(txData)
+
+	tpl := &txbuilder.Template{Transaction: &tx}
+	for range txData.Inputs {
+		tpl.SigningInstructions = append(tpl.SigningInstructions, &txbuilder.SigningInstruction{})
+	}
+	return tpl
+}
+
+func TestRoundTripP2WPKH(t *testing.T) {
+	tpl := newUnsignedTemplate(1)
+	tpl.SigningInstructions[0].WitnessComponents = []txbuilder.Witness{
+		&txbuilder.SignatureWitness{Quorum: 1, Sigs: [][]byte{[]byte("sig-a")}},
+	}
+
+	raw, err := Encode(tpl)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	got, err := Decode(raw)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.Transaction.ID != tpl.Transaction.ID {
+		t.Fatalf("unsigned transaction did not survive the round trip")
+	}
+	if len(got.SigningInstructions) != 1 {
+		t.Fatalf("expected 1 signing instruction, got %d", len(got.SigningInstructions))
+	}
+}
+
+func TestRoundTripP2WSHMultisig(t *testing.T) {
+	tpl := newUnsignedTemplate(1)
+	tpl.SigningInstructions[0].WitnessComponents = []txbuilder.Witness{
+		&txbuilder.SignatureWitness{
+			Quorum: 2,
+			Keys:   []string{"key-a", "key-b", "key-c"},
+			Sigs:   [][]byte{[]byte("sig-1"), nil, []byte("sig-3")},
+		},
+	}
+
+	raw, err := Encode(tpl)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got, err := Decode(raw)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(got.SigningInstructions) != 1 || len(got.SigningInstructions[0].WitnessComponents) != 1 {
+		t.Fatalf("multisig witness component did not survive the round trip: %+v", got.SigningInstructions)
+	}
+
+	sw, ok := got.SigningInstructions[0].WitnessComponents[0].(*txbuilder.SignatureWitness)
+	if !ok {
+		t.Fatalf("expected a *txbuilder.SignatureWitness, got %T", got.SigningInstructions[0].WitnessComponents[0])
+	}
+	if sw.Quorum != 2 {
+		t.Fatalf("expected quorum 2, got %d", sw.Quorum)
+	}
+	if len(sw.Sigs) != 3 || string(sw.Sigs[0]) != "sig-1" || sw.Sigs[1] != nil || string(sw.Sigs[2]) != "sig-3" {
+		t.Fatalf("signature slots did not survive the round trip field-by-field: %q", sw.Sigs)
+	}
+
+	var keys []string
+	if err := reencodeJSON(sw.Keys, &keys); err != nil {
+		t.Fatalf("decoding Keys: %v", err)
+	}
+	if len(keys) != 3 || keys[0] != "key-a" || keys[1] != "key-b" || keys[2] != "key-c" {
+		t.Fatalf("Keys did not survive the round trip, got %v", keys)
+	}
+}
+
+// TestRoundTripMultipleWitnessComponents covers an input with more than one
+// witness component, exercising that each component's field-level entries
+// (kind, quorum, keys, and per-slot signatures) are keyed by position and
+// don't bleed into one another.
+func TestRoundTripMultipleWitnessComponents(t *testing.T) {
+	tpl := newUnsignedTemplate(1)
+	tpl.SigningInstructions[0].WitnessComponents = []txbuilder.Witness{
+		&txbuilder.SignatureWitness{Quorum: 1, Sigs: [][]byte{[]byte("sig-0")}},
+		&txbuilder.RawTxSigWitness{Quorum: 1},
+		&txbuilder.SignatureWitness{Quorum: 2, Sigs: [][]byte{nil, []byte("sig-2")}},
+	}
+
+	raw, err := Encode(tpl)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got, err := Decode(raw)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	components := got.SigningInstructions[0].WitnessComponents
+	if len(components) != 3 {
+		t.Fatalf("expected 3 witness components, got %d", len(components))
+	}
+
+	first, ok := components[0].(*txbuilder.SignatureWitness)
+	if !ok || len(first.Sigs) != 1 || string(first.Sigs[0]) != "sig-0" {
+		t.Fatalf("component 0 did not survive the round trip: %+v", components[0])
+	}
+	if _, ok := components[1].(*txbuilder.RawTxSigWitness); !ok {
+		t.Fatalf("component 1 expected *txbuilder.RawTxSigWitness, got %T", components[1])
+	}
+	third, ok := components[2].(*txbuilder.SignatureWitness)
+	if !ok || len(third.Sigs) != 2 || third.Sigs[0] != nil || string(third.Sigs[1]) != "sig-2" {
+		t.Fatalf("component 2 did not survive the round trip: %+v", components[2])
+	}
+}
+
+func TestRoundTripPeginClaim(t *testing.T) {
+	tpl := newUnsignedTemplate(1)
+	tpl.Transaction.Inputs[0].IsPegin = true
+	tpl.Transaction.Inputs[0].Peginwitness = [][]byte{
+		[]byte("100000000"),
+		[]byte("genesis-hash"),
+		[]byte("claim-script"),
+		[]byte("raw-mainchain-tx"),
+		[]byte("tx-out-proof"),
+	}
+
+	raw, err := Encode(tpl)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got, err := Decode(raw)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if !got.Transaction.Inputs[0].IsPegin {
+		t.Fatal("IsPegin did not survive the round trip")
+	}
+	if len(got.Transaction.Inputs[0].Peginwitness) != len(tpl.Transaction.Inputs[0].Peginwitness) {
+		t.Fatalf("peginwitness stack length changed across the round trip: got %d want %d",
+			len(got.Transaction.Inputs[0].Peginwitness), len(tpl.Transaction.Inputs[0].Peginwitness))
+	}
+	for i, want := range tpl.Transaction.Inputs[0].Peginwitness {
+		if string(got.Transaction.Inputs[0].Peginwitness[i]) != string(want) {
+			t.Fatalf("peginwitness[%d] changed: got %q want %q", i, got.Transaction.Inputs[0].Peginwitness[i], want)
+		}
+	}
+}
+
+func TestCombineMergesPartialSignatures(t *testing.T) {
+	signerA := newUnsignedTemplate(1)
+	signerA.SigningInstructions[0].WitnessComponents = []txbuilder.Witness{
+		&txbuilder.SignatureWitness{Quorum: 2, Sigs: [][]byte{[]byte("sig-1"), nil}},
+	}
+
+	signerB := newUnsignedTemplate(1)
+	signerB.SigningInstructions[0].WitnessComponents = []txbuilder.Witness{
+		&txbuilder.SignatureWitness{Quorum: 2, Sigs: [][]byte{nil, []byte("sig-2")}},
+	}
+
+	merged, err := Combine([]*txbuilder.Template{signerA, signerB})
+	if err != nil {
+		t.Fatalf("Combine: %v", err)
+	}
+	if len(merged.SigningInstructions) != 1 {
+		t.Fatalf("expected 1 merged signing instruction, got %d", len(merged.SigningInstructions))
+	}
+
+	components := merged.SigningInstructions[0].WitnessComponents
+	if len(components) != 1 {
+		t.Fatalf("expected 1 merged witness component, got %d", len(components))
+	}
+	sw, ok := components[0].(*txbuilder.SignatureWitness)
+	if !ok {
+		t.Fatalf("expected a *txbuilder.SignatureWitness, got %T", components[0])
+	}
+	if len(sw.Sigs) != 2 || string(sw.Sigs[0]) != "sig-1" || string(sw.Sigs[1]) != "sig-2" {
+		t.Fatalf("expected merged Sigs [sig-1 sig-2], got %q", sw.Sigs)
+	}
+
+	if _, err := Finalize(merged); err != nil {
+		t.Fatalf("Finalize: expected the quorum-2 template to finalize once both sigs are merged, got %v", err)
+	}
+}
+
+func TestCombineLeavesQuorumUnsatisfiedUntilEveryShareArrives(t *testing.T) {
+	signerA := newUnsignedTemplate(1)
+	signerA.SigningInstructions[0].WitnessComponents = []txbuilder.Witness{
+		&txbuilder.SignatureWitness{Quorum: 2, Sigs: [][]byte{[]byte("sig-1"), nil, nil}},
+	}
+
+	merged, err := Combine([]*txbuilder.Template{signerA})
+	if err != nil {
+		t.Fatalf("Combine: %v", err)
+	}
+
+	if _, err := Finalize(merged); err != ErrIncomplete {
+		t.Fatalf("expected ErrIncomplete with only 1 of 2 required signatures, got %v", err)
+	}
+}
+
+func TestCombineRejectsMismatchedTransactions(t *testing.T) {
+	a := newUnsignedTemplate(1)
+	b := newUnsignedTemplate(2)
+
+	if _, err := Combine([]*txbuilder.Template{a, b}); err != ErrVersionMismatch {
+		t.Fatalf("expected ErrVersionMismatch, got %v", err)
+	}
+}
+
+func TestFinalizeRequiresEverySignature(t *testing.T) {
+	tpl := newUnsignedTemplate(1)
+	if _, err := Finalize(tpl); err != ErrIncomplete {
+		t.Fatalf("expected ErrIncomplete for an unsigned template, got %v", err)
+	}
+
+	tpl.SigningInstructions[0].WitnessComponents = []txbuilder.Witness{
+		&txbuilder.SignatureWitness{Quorum: 1, Sigs: [][]byte{[]byte("sig")}},
+	}
+	tx, err := Finalize(tpl)
+	if err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+	if tx != tpl.Transaction {
+		t.Fatal("Finalize should return the template's own transaction")
+	}
+}
+
+// TestDecodeRejectsOversizedLengthPrefix guards against a length-prefixed
+// value claiming far more bytes than the stream actually carries.
+// decode-template/combine-templates feed attacker-supplied hex straight
+// into Decode, so a handful of malicious bytes must not be able to drive
+// readVarBytes's make([]byte, n) into a multi-GB allocation attempt.
+func TestDecodeRejectsOversizedLengthPrefix(t *testing.T) {
+	raw := append([]byte{}, magic...)
+	raw = append(raw, 0, 0, 0, 0) // numIn = 0
+	raw = append(raw, 0, 0, 0, 0) // numOut = 0
+
+	// Global map: one key-type entry whose length prefix claims far more
+	// bytes than actually follow it in the stream.
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], ^uint64(0)>>1) // a huge but validly-encoded length
+	raw = append(raw, lenBuf[:n]...)
+	raw = append(raw, 0x00) // far short of the claimed length
+
+	if _, err := Decode(raw); err == nil {
+		t.Fatal("expected an oversized length prefix to be rejected, not allocated")
+	}
+}
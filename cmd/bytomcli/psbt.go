@@ -0,0 +1,121 @@
+// Command bytomcli offers offline operations on the PSBT-style portable
+// transaction templates produced by blockchain/txbuilder/psbt, for signing
+// workflows (hardware wallets, air-gapped co-signers) that don't have
+// access to a running bytomd node.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bytom/blockchain/txbuilder"
+	"github.com/bytom/blockchain/txbuilder/psbt"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "decode-template":
+		err = runDecodeTemplate(os.Args[2:])
+	case "combine-templates":
+		err = runCombineTemplates(os.Args[2:])
+	case "finalize-template":
+		err = runFinalizeTemplate(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  bytomcli decode-template <hex-template>        decode a PSBT-style template to JSON
+  bytomcli combine-templates <hex-template>...   merge partial signatures from co-signers
+  bytomcli finalize-template <hex-template>      finalize a fully-signed template to a raw tx`)
+}
+
+func decodeHexTemplate(arg string) (*txbuilder.Template, error) {
+	raw, err := hex.DecodeString(arg)
+	if err != nil {
+		return nil, err
+	}
+	return psbt.Decode(raw)
+}
+
+func runDecodeTemplate(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("decode-template takes exactly one hex-encoded template")
+	}
+	tpl, err := decodeHexTemplate(args[0])
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(tpl, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+func runCombineTemplates(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("combine-templates takes one or more hex-encoded templates")
+	}
+
+	templates := make([]*txbuilder.Template, 0, len(args))
+	for _, arg := range args {
+		tpl, err := decodeHexTemplate(arg)
+		if err != nil {
+			return err
+		}
+		templates = append(templates, tpl)
+	}
+
+	merged, err := psbt.Combine(templates)
+	if err != nil {
+		return err
+	}
+
+	out, err := psbt.Encode(merged)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(out))
+	return nil
+}
+
+func runFinalizeTemplate(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("finalize-template takes exactly one hex-encoded template")
+	}
+	tpl, err := decodeHexTemplate(args[0])
+	if err != nil {
+		return err
+	}
+
+	tx, err := psbt.Finalize(tpl)
+	if err != nil {
+		return err
+	}
+
+	data, err := tx.TxData.MarshalText()
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,214 @@
+package mainchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RPCClient speaks the subset of the Bitcoin Core JSON-RPC API the header
+// poller needs: the current best block hash, and headers by hash.
+type RPCClient struct {
+	url  string
+	user string
+	pass string
+	hc   *http.Client
+}
+
+// NewRPCClient builds a client against cfg's RPC endpoint.
+func NewRPCClient(cfg Config) *RPCClient {
+	return &RPCClient{
+		url:  cfg.RPCURL,
+		user: cfg.RPCUser,
+		pass: cfg.RPCPass,
+		hc:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *RPCClient) call(method string, params []interface{}, out interface{}) error {
+	body, err := json.Marshal(rpcRequest{JSONRPC: "1.0", ID: "bytom-mainchain", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.pass)
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var rpcResp rpcResponse
+	if err := json.Unmarshal(raw, &rpcResp); err != nil {
+		return err
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("mainchain rpc %s: %s", method, rpcResp.Error.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(rpcResp.Result, out)
+}
+
+// BestBlockHash returns the mainchain's current tip hash.
+func (c *RPCClient) BestBlockHash() (string, error) {
+	var hash string
+	err := c.call("getbestblockhash", nil, &hash)
+	return hash, err
+}
+
+// BlockHeaderHex fetches the raw 80-byte header for hash, hex-encoded.
+func (c *RPCClient) BlockHeaderHex(hash string) (string, error) {
+	var raw string
+	err := c.call("getblockheader", []interface{}{hash, false}, &raw)
+	return raw, err
+}
+
+// BlockCount returns the mainchain's current best block height.
+func (c *RPCClient) BlockCount() (uint64, error) {
+	var height uint64
+	err := c.call("getblockcount", nil, &height)
+	return height, err
+}
+
+// BlockHashAtHeight returns the hash of the mainchain block at height.
+func (c *RPCClient) BlockHashAtHeight(height uint64) (string, error) {
+	var hash string
+	err := c.call("getblockhash", []interface{}{height}, &hash)
+	return hash, err
+}
+
+// Poller periodically fetches the mainchain tip and feeds new headers into a
+// HeaderChain, so claimPeginTx can check confirmations without blocking on
+// an RPC round-trip.
+type Poller struct {
+	client   *RPCClient
+	chain    *HeaderChain
+	interval time.Duration
+	height   uint64
+	stop     chan struct{}
+}
+
+// NewPoller builds a Poller that keeps chain fed from client every interval.
+func NewPoller(client *RPCClient, chain *HeaderChain, interval time.Duration) *Poller {
+	return &Poller{
+		client:   client,
+		chain:    chain,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Run blocks, polling until Stop is called. Intended to be run in its own
+// goroutine, e.g. `go poller.Run()`.
+func (p *Poller) Run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.pollOnce(); err != nil {
+				log.WithField("err", err).Warn("mainchain header poll failed")
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Stop terminates the polling loop started by Run.
+func (p *Poller) Stop() {
+	close(p.stop)
+}
+
+// pollOnce walks from the last header we have (exclusive) up to the
+// mainchain's current tip (inclusive), fetching every height in between.
+// Fetching only the tip would mean any tick where more than one block
+// landed since the last poll - a perfectly normal catch-up after downtime,
+// or just a burst - hands HeaderChain a header whose PrevHash doesn't match
+// its stored tip. HeaderChain treats that as a reorg, fails to find a fork
+// point for a gap instead of an orphaned branch, and wipes the whole
+// tracked window.
+func (p *Poller) pollOnce() error {
+	tipHeight, err := p.client.BlockCount()
+	if err != nil {
+		return err
+	}
+
+	startHeight := uint64(0)
+	if existing := p.chain.Tip(); existing != nil {
+		if tipHeight <= existing.Height {
+			return nil
+		}
+		startHeight = existing.Height + 1
+	}
+
+	for height := startHeight; height <= tipHeight; height++ {
+		hash, err := p.client.BlockHashAtHeight(height)
+		if err != nil {
+			return err
+		}
+
+		headerHex, err := p.client.BlockHeaderHex(hash)
+		if err != nil {
+			return err
+		}
+
+		raw, err := hex.DecodeString(headerHex)
+		if err != nil {
+			return err
+		}
+		if len(raw) < blockHeaderSize {
+			return fmt.Errorf("mainchain: short header for block %s", hash)
+		}
+
+		// PrevHash keeps the header's wire byte order, the same order
+		// doubleSha256 produces Hash in, so consecutive headers chain.
+		var prevHash [32]byte
+		copy(prevHash[:], raw[4:36])
+
+		p.chain.AddHeader(&BlockHeader{
+			Height:     height,
+			Hash:       doubleSha256(raw[:blockHeaderSize]),
+			PrevHash:   prevHash,
+			MerkleRoot: reverse32(raw[36:68]),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package mainchain
+
+import "time"
+
+// Config controls how the mainchain header chain is kept up to date.
+type Config struct {
+	// RPCURL is the mainchain node's JSON-RPC endpoint, e.g.
+	// "http://127.0.0.1:8332".
+	RPCURL string `json:"rpc_url"`
+	// RPCUser/RPCPass authenticate against RPCURL with HTTP basic auth.
+	RPCUser string `json:"rpc_user"`
+	RPCPass string `json:"rpc_pass"`
+	// PollInterval controls how often the node polls RPCURL for new tip
+	// headers.
+	PollInterval time.Duration `json:"poll_interval"`
+	// MinConfirmations is how many mainchain blocks must bury a pegin
+	// transaction before claimPeginTx will accept its proof.
+	MinConfirmations uint64 `json:"min_confirmations"`
+	// MaxHeaders bounds how many recent headers HeaderChain retains.
+	MaxHeaders int `json:"max_headers"`
+}
+
+// DefaultConfig mirrors the defaults used by the mainchain node itself:
+// poll every 30s and require 6 confirmations, the same depth Bitcoin Core
+// treats as final for most purposes.
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:     30 * time.Second,
+		MinConfirmations: 6,
+		MaxHeaders:       2016,
+	}
+}
@@ -0,0 +1,23 @@
+package mainchain
+
+import "github.com/bytom/errors"
+
+var (
+	// ErrInvalidProof is returned when a tx-out-proof fails to parse, or its
+	// merkle branch does not recompute to the merkle root of the proof's own
+	// header or does not cover the claimed transaction. A well-formed proof
+	// for a block this node does not track gets ErrUnknownHeader instead.
+	ErrInvalidProof = errors.New("mainchain: tx-out-proof is malformed or does not prove the claimed transaction")
+
+	// ErrInsufficientConfirmations is returned when the proof resolves to a
+	// known header, but that header has not reached the required depth yet.
+	ErrInsufficientConfirmations = errors.New("mainchain: pegin transaction does not have enough confirmations")
+
+	// ErrProofReplayed is returned when the same mainchain txid + claim
+	// script pair has already been used to claim a pegin.
+	ErrProofReplayed = errors.New("mainchain: pegin proof has already been claimed")
+
+	// ErrUnknownHeader is returned when a proof references a block hash this
+	// node has not synced yet.
+	ErrUnknownHeader = errors.New("mainchain: block referenced by proof is not tracked")
+)
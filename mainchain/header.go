@@ -0,0 +1,136 @@
+package mainchain
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// BlockHeader is a minimal Bitcoin-style mainchain header. Only the fields
+// needed to validate a merkle branch and to chain headers together are kept.
+type BlockHeader struct {
+	Height     uint64
+	Hash       [32]byte
+	PrevHash   [32]byte
+	MerkleRoot [32]byte
+}
+
+// HeaderChain keeps a rolling window of recently-seen mainchain headers so
+// claimPeginTx can recompute a merkle root and check how deep it is buried
+// without round-tripping to the mainchain RPC on every claim.
+//
+// It is fed by a Poller polling loop (see client.go) and supports reorgs: a
+// header submitted with a PrevHash that does not match the current tip rolls
+// back to the fork point before appending the new branch.
+type HeaderChain struct {
+	mu      sync.RWMutex
+	maxSize int
+	byHash  map[[32]byte]*BlockHeader
+	order   []*BlockHeader // oldest first
+}
+
+// NewHeaderChain returns an empty chain that retains at most maxSize headers.
+func NewHeaderChain(maxSize int) *HeaderChain {
+	if maxSize <= 0 {
+		maxSize = 2016
+	}
+	return &HeaderChain{
+		maxSize: maxSize,
+		byHash:  make(map[[32]byte]*BlockHeader),
+	}
+}
+
+// Tip returns the highest header currently tracked, or nil if empty.
+func (c *HeaderChain) Tip() *BlockHeader {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.order) == 0 {
+		return nil
+	}
+	return c.order[len(c.order)-1]
+}
+
+// HeaderByHash looks up a tracked header by its block hash.
+func (c *HeaderChain) HeaderByHash(hash [32]byte) (*BlockHeader, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	h, ok := c.byHash[hash]
+	return h, ok
+}
+
+// Confirmations returns how many blocks have been built on top of the given
+// header, i.e. 1 if it is the tip. Returns 0, false if the header is unknown.
+func (c *HeaderChain) Confirmations(hash [32]byte) (uint64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	h, ok := c.byHash[hash]
+	if !ok {
+		return 0, false
+	}
+	tip := c.order[len(c.order)-1]
+	return tip.Height - h.Height + 1, true
+}
+
+// AddHeader appends a new tip, rolling back any headers that were building on
+// a now-abandoned branch (a reorg) before doing so.
+func (c *HeaderChain) AddHeader(h *BlockHeader) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.order) > 0 {
+		tip := c.order[len(c.order)-1]
+		if tip.Hash != h.PrevHash {
+			c.reorgTo(h.PrevHash)
+		}
+	}
+
+	c.order = append(c.order, h)
+	c.byHash[h.Hash] = h
+
+	for len(c.order) > c.maxSize {
+		delete(c.byHash, c.order[0].Hash)
+		c.order = c.order[1:]
+	}
+}
+
+// reorgTo drops every tracked header after forkHash, leaving forkHash as the
+// tip. Must be called with mu held.
+func (c *HeaderChain) reorgTo(forkHash [32]byte) {
+	for i := len(c.order) - 1; i >= 0; i-- {
+		if c.order[i].Hash == forkHash {
+			for _, dropped := range c.order[i+1:] {
+				delete(c.byHash, dropped.Hash)
+			}
+			c.order = c.order[:i+1]
+			return
+		}
+	}
+
+	// fork point predates our window entirely; nothing we can do but wait
+	// for new headers to repopulate the chain from here.
+	for _, h := range c.order {
+		delete(c.byHash, h.Hash)
+	}
+	c.order = c.order[:0]
+}
+
+// doubleSha256 is Bitcoin's SHA256d: two rounds of plain SHA-256, not
+// Bytom's own SHA3. Mainchain block hashes, merkle roots, and txids are all
+// computed this way upstream, so this has to match exactly for a merkle
+// proof recomputed here to ever agree with a genuine mainchain header.
+func doubleSha256(parts ...[]byte) [32]byte {
+	first := sha256.Sum256(concat(parts))
+	return sha256.Sum256(first[:])
+}
+
+func concat(parts [][]byte) []byte {
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+	out := make([]byte, 0, n)
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
@@ -0,0 +1,268 @@
+package mainchain
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/bytom/protocol/bc"
+)
+
+// buildSingleTxProof constructs a valid tx-out-proof for a block containing
+// exactly one transaction, so the merkle root equals the txid itself.
+func buildSingleTxProof(t *testing.T, txid [32]byte, prevHash, merkleRoot [32]byte) string {
+	t.Helper()
+
+	buf := make([]byte, 0, blockHeaderSize+64)
+	buf = append(buf, make([]byte, 4)...) // version
+	buf = append(buf, reverseBytes(prevHash[:])...)
+	buf = append(buf, reverseBytes(merkleRoot[:])...)
+	buf = append(buf, make([]byte, 12)...) // time, bits, nonce
+
+	buf = append(buf, 1, 0, 0, 0) // numTxns = 1
+	buf = append(buf, 1)          // hash count
+	buf = append(buf, reverseBytes(txid[:])...)
+	buf = append(buf, 1) // flag byte count
+	buf = append(buf, 1) // flag bits: bit0 = 1 (this hash is a matched leaf)
+
+	return hex.EncodeToString(buf)
+}
+
+// proofBlockHash returns the block hash of the header embedded in proof, so
+// a test can track that block in a HeaderChain.
+func proofBlockHash(t *testing.T, proof string) [32]byte {
+	t.Helper()
+
+	mb, err := ParseTxOutProof(proof)
+	if err != nil {
+		t.Fatalf("ParseTxOutProof: %v", err)
+	}
+	return mb.Header.Hash
+}
+
+func TestParseTxOutProofMalformed(t *testing.T) {
+	cases := []string{
+		"",
+		"00",
+		"zz", // not even hex
+	}
+	for _, c := range cases {
+		if _, err := ParseTxOutProof(c); err == nil {
+			t.Errorf("ParseTxOutProof(%q): expected error, got nil", c)
+		}
+	}
+}
+
+// TestParseTxOutProofRejectsOversizedCounts guards against a proof that
+// claims far more hashes or flag bytes than it actually carries. TxOutProof
+// is attacker-controlled input (it arrives over the pegin-claim API), so a
+// crafted hashCount/flagCount driving make([][32]byte, hashCount) straight
+// from the wire would let a ~20-byte request force a multi-GB allocation
+// attempt - not a recoverable error, since Go's OOM path kills the process.
+func TestParseTxOutProofRejectsOversizedCounts(t *testing.T) {
+	header := make([]byte, blockHeaderSize)
+	numTxns := []byte{1, 0, 0, 0}
+	hugeVarInt := append([]byte{0xff}, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff) // 0xffffffffffffffff
+
+	t.Run("hash count", func(t *testing.T) {
+		proof := append([]byte{}, header...)
+		proof = append(proof, numTxns...)
+		proof = append(proof, hugeVarInt...) // hash count
+		if _, err := ParseTxOutProof(hex.EncodeToString(proof)); err == nil {
+			t.Fatal("expected an oversized hash count to be rejected, not allocated")
+		}
+	})
+
+	t.Run("flag count", func(t *testing.T) {
+		proof := append([]byte{}, header...)
+		proof = append(proof, numTxns...)
+		proof = append(proof, 0) // hash count = 0
+		proof = append(proof, hugeVarInt...) // flag count
+		if _, err := ParseTxOutProof(hex.EncodeToString(proof)); err == nil {
+			t.Fatal("expected an oversized flag count to be rejected, not allocated")
+		}
+	})
+}
+
+func TestMerkleBlockVerify(t *testing.T) {
+	var txid [32]byte
+	txid[0] = 0xaa
+
+	proof := buildSingleTxProof(t, txid, [32]byte{}, txid)
+	mb, err := ParseTxOutProof(proof)
+	if err != nil {
+		t.Fatalf("ParseTxOutProof: %v", err)
+	}
+	if err := mb.Verify(txid); err != nil {
+		t.Fatalf("Verify: expected success, got %v", err)
+	}
+
+	var wrongTxid [32]byte
+	wrongTxid[0] = 0xbb
+	if err := mb.Verify(wrongTxid); err == nil {
+		t.Fatal("Verify: expected error for a txid not covered by the proof")
+	}
+}
+
+func TestMerkleBlockVerifyRootMismatch(t *testing.T) {
+	var txid, fakeRoot [32]byte
+	txid[0] = 0xaa
+	fakeRoot[0] = 0xff
+
+	proof := buildSingleTxProof(t, txid, [32]byte{}, fakeRoot)
+	mb, err := ParseTxOutProof(proof)
+	if err != nil {
+		t.Fatalf("ParseTxOutProof: %v", err)
+	}
+	if err := mb.Verify(txid); err == nil {
+		t.Fatal("Verify: expected merkle root mismatch to be rejected")
+	}
+}
+
+func newTestHeader(height uint64, hash, prev byte) *BlockHeader {
+	h := &BlockHeader{Height: height}
+	h.Hash[0] = hash
+	h.PrevHash[0] = prev
+	return h
+}
+
+func TestVerifierConfirmationDepth(t *testing.T) {
+	var txid [32]byte
+	txid[0] = 0xaa
+	proof := buildSingleTxProof(t, txid, [32]byte{}, txid)
+
+	// The proof's own block is tracked as the first header, so every header
+	// added on top of it adds one confirmation.
+	chain := NewHeaderChain(100)
+	genesis := &BlockHeader{Height: 0, Hash: proofBlockHash(t, proof), MerkleRoot: txid}
+	chain.AddHeader(genesis)
+
+	v := NewVerifier(chain, 3)
+	mainchainTxID := bc.NewHash(txid)
+
+	if err := v.VerifyClaim(proof, mainchainTxID, "script-a"); err != ErrInsufficientConfirmations {
+		t.Fatalf("expected ErrInsufficientConfirmations with only 1 confirmation, got %v", err)
+	}
+
+	chain.AddHeader(&BlockHeader{Height: 1, Hash: [32]byte{2}, PrevHash: genesis.Hash})
+	chain.AddHeader(newTestHeader(2, 3, 2))
+
+	if err := v.VerifyClaim(proof, mainchainTxID, "script-a"); err != nil {
+		t.Fatalf("expected success with 3 confirmations, got %v", err)
+	}
+}
+
+func TestVerifierRejectsReplayedProof(t *testing.T) {
+	var txid [32]byte
+	txid[0] = 0xaa
+	proof := buildSingleTxProof(t, txid, [32]byte{}, txid)
+
+	chain := NewHeaderChain(100)
+	genesis := &BlockHeader{Height: 0, Hash: proofBlockHash(t, proof), MerkleRoot: txid}
+	chain.AddHeader(genesis)
+	chain.AddHeader(&BlockHeader{Height: 1, Hash: [32]byte{2}, PrevHash: genesis.Hash})
+
+	v := NewVerifier(chain, 1)
+	mainchainTxID := bc.NewHash(txid)
+
+	if err := v.VerifyClaim(proof, mainchainTxID, "script-a"); err != nil {
+		t.Fatalf("first claim should succeed, got %v", err)
+	}
+	if err := v.VerifyClaim(proof, mainchainTxID, "script-a"); err != ErrProofReplayed {
+		t.Fatalf("replayed claim should be rejected, got %v", err)
+	}
+	// A different claim script over the same mainchain txid is a distinct
+	// claim and must not be blocked by the first one's dedup entry.
+	if err := v.VerifyClaim(proof, mainchainTxID, "script-b"); err != nil {
+		t.Fatalf("claim with a different claim script should succeed, got %v", err)
+	}
+}
+
+func TestHeaderChainReorg(t *testing.T) {
+	chain := NewHeaderChain(100)
+	chain.AddHeader(newTestHeader(0, 1, 0))
+	chain.AddHeader(newTestHeader(1, 2, 1))
+	chain.AddHeader(newTestHeader(2, 3, 2))
+
+	if _, ok := chain.HeaderByHash([32]byte{3}); !ok {
+		t.Fatal("expected height-2 header to be tracked before reorg")
+	}
+
+	// New block at height 2 builds on height-1's hash instead of the old
+	// tip, simulating a one-block reorg.
+	reorgHeader := newTestHeader(2, 4, 2)
+	chain.AddHeader(reorgHeader)
+
+	if _, ok := chain.HeaderByHash([32]byte{3}); ok {
+		t.Fatal("orphaned header should have been dropped on reorg")
+	}
+	if _, ok := chain.HeaderByHash([32]byte{4}); !ok {
+		t.Fatal("new tip should be tracked after reorg")
+	}
+	if tip := chain.Tip(); tip == nil || tip.Hash != reorgHeader.Hash {
+		t.Fatal("chain tip should be the reorg header")
+	}
+}
+
+// TestMerkleBlockVerifyGenesisBlock runs ParseTxOutProof/Verify against the
+// real Bitcoin mainnet genesis block (block 0) instead of a synthetic
+// fixture: the header bytes, block hash, and coinbase txid below are taken
+// verbatim from chain data. A doubleSha256 that only agreed with itself
+// (e.g. the sha3pool stand-in this package used before) would fail this
+// test even though every other test here, built from self-consistent
+// fixtures, would still pass.
+func TestMerkleBlockVerifyGenesisBlock(t *testing.T) {
+	const genesisHeaderHex = "01000000" +
+		"0000000000000000000000000000000000000000000000000000000000000000" +
+		"3ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a" +
+		"29ab5f49" + "ffff001d" + "1dac2b7c"
+	const genesisBlockHash = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	const genesisCoinbaseTxID = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+
+	header, err := hex.DecodeString(genesisHeaderHex)
+	if err != nil || len(header) != blockHeaderSize {
+		t.Fatalf("bad genesis header fixture: err=%v len=%d", err, len(header))
+	}
+	txidWire, err := hex.DecodeString(genesisCoinbaseTxID)
+	if err != nil {
+		t.Fatalf("bad genesis coinbase txid fixture: %v", err)
+	}
+
+	// Genesis has exactly one transaction, so its tx-out-proof has the same
+	// shape buildSingleTxProof produces: numTxns=1, one hash (the coinbase
+	// txid, in wire byte order), one flag byte with the leaf-matched bit set.
+	proof := append([]byte{}, header...)
+	proof = append(proof, 1, 0, 0, 0) // numTxns
+	proof = append(proof, 1)          // hash count
+	proof = append(proof, reverseBytes(txidWire)...)
+	proof = append(proof, 1) // flag byte count
+	proof = append(proof, 1) // flag bits: bit0 = 1 (this hash is a matched leaf)
+
+	mb, err := ParseTxOutProof(hex.EncodeToString(proof))
+	if err != nil {
+		t.Fatalf("ParseTxOutProof: %v", err)
+	}
+
+	wantHashWire, err := hex.DecodeString(genesisBlockHash)
+	if err != nil {
+		t.Fatalf("bad genesis block hash fixture: %v", err)
+	}
+	var wantHash [32]byte
+	copy(wantHash[:], reverseBytes(wantHashWire))
+	if mb.Header.Hash != wantHash {
+		t.Fatalf("genesis block hash mismatch: got %x want %x", mb.Header.Hash, wantHash)
+	}
+
+	var txid [32]byte
+	copy(txid[:], txidWire)
+	if err := mb.Verify(txid); err != nil {
+		t.Fatalf("Verify: expected the real genesis coinbase proof to verify, got %v", err)
+	}
+}
+
+func reverseBytes(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[len(b)-1-i]
+	}
+	return out
+}
@@ -0,0 +1,264 @@
+package mainchain
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/bytom/errors"
+)
+
+const blockHeaderSize = 80 // version(4) + prevHash(32) + merkleRoot(32) + time(4) + bits(4) + nonce(4)
+
+// MerkleBlock is the parsed form of a Bitcoin Core `gettxoutproof` result: a
+// block header plus a partial merkle tree proving that one or more
+// transactions are included in that block.
+type MerkleBlock struct {
+	Header  BlockHeader
+	NumTxns uint32
+	hashes  [][32]byte
+	flags   []byte
+}
+
+// ParseTxOutProof decodes the hex-encoded proof produced by the mainchain's
+// `gettxoutproof` RPC into a MerkleBlock.
+func ParseTxOutProof(proofHex string) (*MerkleBlock, error) {
+	raw, err := hex.DecodeString(proofHex)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidProof, err.Error())
+	}
+	if len(raw) < blockHeaderSize+4 {
+		return nil, errors.WithDetail(ErrInvalidProof, "proof shorter than a block header")
+	}
+
+	mb := &MerkleBlock{}
+	mb.Header.PrevHash = reverse32(raw[4:36])
+	mb.Header.MerkleRoot = reverse32(raw[36:68])
+	mb.Header.Hash = doubleSha256(raw[:blockHeaderSize])
+
+	r := &byteReader{buf: raw[blockHeaderSize:]}
+	numTxns, err := r.readUint32LE()
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidProof, err.Error())
+	}
+	mb.NumTxns = numTxns
+
+	hashCount, err := r.readVarInt()
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidProof, err.Error())
+	}
+	// Each hash consumes 32 bytes off the wire, so hashCount can never
+	// legitimately exceed the bytes actually left in the proof. Without this
+	// check a proof a few bytes long claiming a huge hashCount would drive
+	// make([][32]byte, hashCount) to attempt a multi-GB allocation - not a
+	// recoverable error, since Go's OOM path is a fatal runtime error that
+	// takes the whole node down with it.
+	if hashCount > uint64(len(r.buf))/32 {
+		return nil, errors.WithDetail(ErrInvalidProof, "hash count exceeds remaining proof data")
+	}
+	mb.hashes = make([][32]byte, hashCount)
+	for i := range mb.hashes {
+		h, err := r.readHash()
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidProof, err.Error())
+		}
+		mb.hashes[i] = h
+	}
+
+	flagCount, err := r.readVarInt()
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidProof, err.Error())
+	}
+	if flagCount > uint64(len(r.buf)) {
+		return nil, errors.WithDetail(ErrInvalidProof, "flag count exceeds remaining proof data")
+	}
+	mb.flags, err = r.readBytes(int(flagCount))
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidProof, err.Error())
+	}
+
+	return mb, nil
+}
+
+// Verify recomputes the merkle root from the partial tree and confirms that
+// txid is one of the leaves it covers. It returns an error unless the
+// recomputed root matches the header's merkle root exactly.
+func (mb *MerkleBlock) Verify(txid [32]byte) error {
+	if mb.NumTxns == 0 {
+		return errors.WithDetail(ErrInvalidProof, "proof claims zero transactions")
+	}
+
+	t := &merkleTraversal{
+		hashes:  mb.hashes,
+		flags:   mb.flags,
+		numTxns: int(mb.NumTxns),
+	}
+
+	matched := make(map[[32]byte]bool)
+	root, err := t.recurse(0, treeHeight(int(mb.NumTxns)), matched)
+	if err != nil {
+		return errors.Wrap(ErrInvalidProof, err.Error())
+	}
+	if !t.consumedAll() {
+		return errors.WithDetail(ErrInvalidProof, "proof has unused hashes or flag bits")
+	}
+	if root != mb.Header.MerkleRoot {
+		return errors.WithDetail(ErrInvalidProof, "recomputed merkle root does not match block header")
+	}
+	if !matched[txid] {
+		return errors.WithDetail(ErrInvalidProof, "proof does not cover the claimed transaction")
+	}
+	return nil
+}
+
+func treeHeight(numTxns int) int {
+	height := 0
+	for (1 << uint(height)) < numTxns {
+		height++
+	}
+	return height
+}
+
+// merkleTraversal replays the depth-first walk Bitcoin Core uses to both
+// serialize and deserialize a partial merkle tree.
+type merkleTraversal struct {
+	hashes  [][32]byte
+	flags   []byte
+	numTxns int
+	hashIdx int
+	bitIdx  int
+}
+
+func (t *merkleTraversal) consumedAll() bool {
+	return t.hashIdx == len(t.hashes) && (t.bitIdx+7)/8 == len(t.flags)
+}
+
+func (t *merkleTraversal) nextBit() bool {
+	if t.bitIdx/8 >= len(t.flags) {
+		return false
+	}
+	bit := (t.flags[t.bitIdx/8] >> uint(t.bitIdx%8)) & 1
+	t.bitIdx++
+	return bit != 0
+}
+
+func (t *merkleTraversal) nextHash() ([32]byte, error) {
+	if t.hashIdx >= len(t.hashes) {
+		return [32]byte{}, errors.New("ran out of hashes while walking partial merkle tree")
+	}
+	h := t.hashes[t.hashIdx]
+	t.hashIdx++
+	return h, nil
+}
+
+// recurse walks one node of the tree at the given height (leaves are height
+// 0) and position, returning that node's hash. matched collects every leaf
+// txid that the proof flagged as "of interest".
+func (t *merkleTraversal) recurse(pos, height int, matched map[[32]byte]bool) ([32]byte, error) {
+	flag := t.nextBit()
+
+	if height == 0 || !flag {
+		h, err := t.nextHash()
+		if err != nil {
+			return [32]byte{}, err
+		}
+		if height == 0 && flag {
+			matched[h] = true
+		}
+		return h, nil
+	}
+
+	left, err := t.recurse(pos*2, height-1, matched)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	// if the left child is the last node at this level, Bitcoin duplicates
+	// it instead of reading a right child from the wire.
+	right := left
+	if hasRightChild(pos, height, t.numTxns) {
+		right, err = t.recurse(pos*2+1, height-1, matched)
+		if err != nil {
+			return [32]byte{}, err
+		}
+	}
+
+	// Hashes are held in display (byte-reversed) order; Bitcoin hashes the
+	// two children in their internal byte order.
+	l, r := reverse32(left[:]), reverse32(right[:])
+	parent := doubleSha256(l[:], r[:])
+	return reverse32(parent[:]), nil
+}
+
+// hasRightChild reports whether the node at (pos, height) has a right child,
+// i.e. whether pos*2+1 lies inside the level below it (height-1).
+func hasRightChild(pos, height, numTxns int) bool {
+	childHeight := uint(height - 1)
+	width := (numTxns + (1 << childHeight) - 1) >> childHeight
+	return pos*2+1 < width
+}
+
+func reverse32(b []byte) [32]byte {
+	var out [32]byte
+	for i := 0; i < 32; i++ {
+		out[i] = b[31-i]
+	}
+	return out
+}
+
+type byteReader struct {
+	buf []byte
+}
+
+func (r *byteReader) readBytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.buf) {
+		return nil, errors.New("unexpected end of proof")
+	}
+	out := r.buf[:n]
+	r.buf = r.buf[n:]
+	return out, nil
+}
+
+func (r *byteReader) readUint32LE() (uint32, error) {
+	b, err := r.readBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *byteReader) readHash() ([32]byte, error) {
+	b, err := r.readBytes(32)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return reverse32(b), nil
+}
+
+func (r *byteReader) readVarInt() (uint64, error) {
+	b, err := r.readBytes(1)
+	if err != nil {
+		return 0, err
+	}
+	switch b[0] {
+	case 0xfd:
+		v, err := r.readBytes(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint16(v)), nil
+	case 0xfe:
+		v, err := r.readBytes(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint32(v)), nil
+	case 0xff:
+		v, err := r.readBytes(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.LittleEndian.Uint64(v), nil
+	default:
+		return uint64(b[0]), nil
+	}
+}
@@ -0,0 +1,82 @@
+package mainchain
+
+import (
+	"sync"
+
+	"github.com/bytom/protocol/bc"
+)
+
+// claimKey dedups pegin claims by the mainchain txid they spend plus the
+// claim script that unlocked them, mirroring how the mainchain itself
+// prevents a UTXO from being spent twice.
+type claimKey struct {
+	txid        bc.Hash
+	claimScript string
+}
+
+// Verifier ties a HeaderChain to the pegin-specific policy: a minimum
+// confirmation depth and a set of already-claimed proofs.
+type Verifier struct {
+	chain      *HeaderChain
+	minConfirm uint64
+
+	mu     sync.Mutex
+	claimed map[claimKey]bool
+}
+
+// NewVerifier constructs a Verifier backed by chain, requiring minConfirm
+// confirmations (inclusive of the block the pegin transaction landed in)
+// before a claim is accepted.
+func NewVerifier(chain *HeaderChain, minConfirm uint64) *Verifier {
+	return &Verifier{
+		chain:      chain,
+		minConfirm: minConfirm,
+		claimed:    make(map[claimKey]bool),
+	}
+}
+
+// VerifyClaim checks that proof is a well-formed merkle proof for mainchainTxID,
+// that the block it resolves to is tracked and buried under at least
+// minConfirm blocks, and that this exact (txid, claimScript) pair has not
+// been claimed before. On success it marks the pair as claimed so a replay of
+// the same proof is rejected.
+func (v *Verifier) VerifyClaim(proofHex string, mainchainTxID bc.Hash, claimScript string) error {
+	key := claimKey{txid: mainchainTxID, claimScript: claimScript}
+
+	v.mu.Lock()
+	alreadyClaimed := v.claimed[key]
+	v.mu.Unlock()
+	if alreadyClaimed {
+		return ErrProofReplayed
+	}
+
+	mb, err := ParseTxOutProof(proofHex)
+	if err != nil {
+		return err
+	}
+
+	if err := mb.Verify(mainchainTxID.Byte32()); err != nil {
+		return err
+	}
+
+	header, ok := v.chain.HeaderByHash(mb.Header.Hash)
+	if !ok {
+		return ErrUnknownHeader
+	}
+
+	confirmations, ok := v.chain.Confirmations(header.Hash)
+	if !ok || confirmations < v.minConfirm {
+		return ErrInsufficientConfirmations
+	}
+
+	// Re-check under the same lock that marks the pair, so two concurrent
+	// claims of one proof cannot both succeed.
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.claimed[key] {
+		return ErrProofReplayed
+	}
+	v.claimed[key] = true
+
+	return nil
+}